@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/atabilog/logbook/internal/logbook"
 )
@@ -16,6 +17,13 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  add   Add a new logbook entry\n")
 		fmt.Fprintf(os.Stderr, "  list  List all entries\n")
 		fmt.Fprintf(os.Stderr, "  search-tags  Find entries by tags\n")
+		fmt.Fprintf(os.Stderr, "  start  Start a timer: start \"task\" [--tags x,y]\n")
+		fmt.Fprintf(os.Stderr, "  stop   Stop the running timer and save it as an entry\n")
+		fmt.Fprintf(os.Stderr, "  status Show the running timer\n")
+		fmt.Fprintf(os.Stderr, "  timesheet  Sum tracked time per day and tag [--week] [--csv file]\n")
+		fmt.Fprintf(os.Stderr, "  export  Export all entries [--format ics] [--out file]\n")
+		fmt.Fprintf(os.Stderr, "  site    Render a static HTML site: site ./out\n")
+		fmt.Fprintf(os.Stderr, "  archive  Compact old entries into monthly bundles [--older-than 365d] [--keep-tags keep]\n")
 	}
 
 	if len(os.Args) < 2 {
@@ -43,6 +51,85 @@ func main() {
 		logbook.ListEntries()
 	} else if subcommand == "search-tags" {
 		logbook.SearchByTags(os.Args[2:])
+	} else if subcommand == "start" {
+		startCmd := flag.NewFlagSet("start", flag.ExitOnError)
+		tags := startCmd.String("tags", "", "Optional comma-separated list of tags for the entry")
+
+		// the task text comes first, so parse again for flags that follow it
+		startCmd.Parse(os.Args[2:])
+		if startCmd.NArg() == 0 {
+			fmt.Println("To start a timer please specify the task, e.g. logbook start \"task\"")
+			return
+		}
+		text := startCmd.Arg(0)
+		startCmd.Parse(startCmd.Args()[1:])
+
+		if err := logbook.StartTimer(text, *tags); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if subcommand == "stop" {
+		if err := logbook.StopTimer(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if subcommand == "status" {
+		if err := logbook.TimerStatus(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if subcommand == "timesheet" {
+		timesheetCmd := flag.NewFlagSet("timesheet", flag.ExitOnError)
+		week := timesheetCmd.Bool("week", false, "Only include the current week")
+		csvPath := timesheetCmd.String("csv", "", "Also write the timesheet to this CSV file")
+
+		timesheetCmd.Parse(os.Args[2:])
+
+		if err := logbook.PrintTimesheet(*week, *csvPath); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if subcommand == "archive" {
+		archiveCmd := flag.NewFlagSet("archive", flag.ExitOnError)
+		olderThan := archiveCmd.String("older-than", "365d", "Archive entries older than this age (e.g. 365d, 720h)")
+		keepTags := archiveCmd.String("keep-tags", "keep", "Comma-separated tags whose entries are never archived")
+		dryRun := archiveCmd.Bool("dry-run", false, "Only report what would be archived")
+
+		archiveCmd.Parse(os.Args[2:])
+
+		maxAge, err := logbook.ParseAge(*olderThan)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		policy := logbook.RetentionPolicy{MaxAge: maxAge}
+		if *keepTags != "" {
+			policy.KeepTags = strings.Split(*keepTags, ",")
+		}
+		if err := logbook.Archive(policy, *dryRun); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if subcommand == "export" {
+		exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+		format := exportCmd.String("format", "ics", "Export format (ics)")
+		out := exportCmd.String("out", "", "Write to this file instead of stdout")
+
+		exportCmd.Parse(os.Args[2:])
+
+		if err := logbook.Export(*format, *out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if subcommand == "site" {
+		if len(os.Args) < 3 {
+			fmt.Println("To build the site please specify the output directory, e.g. logbook site ./out")
+			return
+		}
+		if err := logbook.BuildSite(os.Args[2]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	} else {
 		flag.Usage()
 		os.Exit(1)
@@ -0,0 +1,220 @@
+package logbook
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	archiveDirName   = "archive"
+	bundleExtension  = ".jsonl.gz"
+	bundleNameLayout = "2006-01"
+)
+
+// RetentionPolicy decides which entries `logbook archive` moves out of the
+// entries directory and into monthly bundles.
+type RetentionPolicy struct {
+	// MaxAge is how old an entry must be before it is archived.
+	MaxAge time.Duration
+	// KeepTags marks entries that stay in the entries directory forever.
+	KeepTags []string
+}
+
+func (p RetentionPolicy) shouldArchive(entry Entry, now time.Time) bool {
+	if hasAnyTag(entry, p.KeepTags) {
+		return false
+	}
+	return now.Sub(entry.Timestamp) > p.MaxAge
+}
+
+// ParseAge parses a positive duration that may also be given in days,
+// e.g. "365d". A zero or negative age would archive every entry.
+func ParseAge(s string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("invalid age %q: must be positive", s)
+	}
+	return age, nil
+}
+
+func archiveDirectory() string {
+	return filepath.Join(entriesDirectory, archiveDirName)
+}
+
+func readBundle(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// writeBundle replaces the bundle at path with entries, one JSON object per
+// line, going through a temporary file so a failed write leaves the old
+// bundle intact.
+func writeBundle(path string, entries []Entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".bundle-*")
+	if err != nil {
+		return fmt.Errorf("error creating bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := gzip.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			tmp.Close()
+			return fmt.Errorf("error writing bundle: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing bundle: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadArchivedEntries reads every bundle in the archive directory, oldest
+// month first. A missing archive directory simply means nothing is archived.
+func loadArchivedEntries() ([]Entry, error) {
+	files, err := os.ReadDir(archiveDirectory())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), bundleExtension) {
+			continue
+		}
+		bundle, err := readBundle(filepath.Join(archiveDirectory(), file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, bundle...)
+	}
+	return entries, nil
+}
+
+// Archive moves the entries selected by policy into gzipped JSON Lines
+// bundles, one per month, under the archive directory. Entries already in a
+// month's bundle are kept. With dryRun set it only reports what would move.
+func Archive(policy RetentionPolicy, dryRun bool) error {
+	names, err := entryFiles()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	byMonth := map[string][]Entry{}
+	filesByMonth := map[string][]string{}
+	for _, name := range names {
+		entry, err := readEntryFile(name)
+		if err != nil {
+			return err
+		}
+		if !policy.shouldArchive(entry, now) {
+			continue
+		}
+		month := entry.Timestamp.Format(bundleNameLayout)
+		byMonth[month] = append(byMonth[month], entry)
+		filesByMonth[month] = append(filesByMonth[month], name)
+	}
+
+	if len(byMonth) == 0 {
+		fmt.Println("Nothing to archive")
+		return nil
+	}
+
+	months := make([]string, 0, len(byMonth))
+	for month := range byMonth {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+
+	if !dryRun {
+		if err := os.MkdirAll(archiveDirectory(), 0755); err != nil {
+			return fmt.Errorf("error creating archive: %w", err)
+		}
+	}
+
+	for _, month := range months {
+		path := filepath.Join(archiveDirectory(), month+bundleExtension)
+		if dryRun {
+			fmt.Printf("Would archive %d entries to %s\n", len(byMonth[month]), path)
+			continue
+		}
+
+		existing, err := readBundle(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		bundle := append(existing, byMonth[month]...)
+		sort.SliceStable(bundle, func(i, j int) bool {
+			return bundle[i].Timestamp.Before(bundle[j].Timestamp)
+		})
+		if err := writeBundle(path, bundle); err != nil {
+			return err
+		}
+
+		// only drop the loose files once the bundle holding them is on disk
+		for _, name := range filesByMonth[month] {
+			if err := os.Remove(filepath.Join(entriesDirectory, name)); err != nil {
+				return fmt.Errorf("error removing %s: %w", name, err)
+			}
+		}
+		fmt.Printf("Archived %d entries to %s\n", len(byMonth[month]), path)
+	}
+	return nil
+}
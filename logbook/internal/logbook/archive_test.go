@@ -0,0 +1,103 @@
+package logbook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	tempDir := t.TempDir()
+	SetEntriesDirectory(tempDir)
+
+	now := time.Now()
+	old := now.AddDate(-2, 0, 0)
+	testEntries := []Entry{
+		{Text: "Old entry", Timestamp: old, Tags: []string{"work"}},
+		{Text: "Old entry same month", Timestamp: old.Add(time.Minute), Tags: []string{}},
+		{Text: "Old but kept", Timestamp: old.Add(2 * time.Minute), Tags: []string{"keep"}},
+		{Text: "Recent entry", Timestamp: now, Tags: []string{"work"}},
+	}
+	for _, entry := range testEntries {
+		if err := saveEntry(entry); err != nil {
+			t.Fatalf("Failed to save test entry: %v", err)
+		}
+	}
+
+	policy := RetentionPolicy{MaxAge: 365 * 24 * time.Hour, KeepTags: []string{"keep"}}
+	if err := Archive(policy, false); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	names, err := entryFiles()
+	if err != nil {
+		t.Fatalf("entryFiles() error = %v", err)
+	}
+	if len(names) != 2 {
+		t.Errorf("Expected 2 loose entries after archiving, got %d", len(names))
+	}
+
+	bundle := filepath.Join(tempDir, archiveDirName, old.Format(bundleNameLayout)+bundleExtension)
+	if _, err := os.Stat(bundle); err != nil {
+		t.Fatalf("Expected bundle %s: %v", bundle, err)
+	}
+
+	entries, err := loadEntries()
+	if err != nil {
+		t.Fatalf("loadEntries() error = %v", err)
+	}
+	if len(entries) != len(testEntries) {
+		t.Errorf("loadEntries() returned %d entries, want %d", len(entries), len(testEntries))
+	}
+
+	// archiving again must not lose what is already bundled
+	if err := saveEntry(Entry{Text: "Late old entry", Timestamp: old.Add(3 * time.Minute), Tags: []string{}}); err != nil {
+		t.Fatalf("Failed to save test entry: %v", err)
+	}
+	if err := Archive(policy, false); err != nil {
+		t.Fatalf("second Archive() error = %v", err)
+	}
+	archived, err := readBundle(bundle)
+	if err != nil {
+		t.Fatalf("readBundle() error = %v", err)
+	}
+	if len(archived) != 3 {
+		t.Errorf("Bundle holds %d entries, want 3", len(archived))
+	}
+
+	if err := SearchByTags([]string{"work"}); err != nil {
+		t.Errorf("SearchByTags() error = %v", err)
+	}
+	if err := ListEntries(); err != nil {
+		t.Errorf("ListEntries() error = %v", err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "365d", want: 365 * 24 * time.Hour},
+		{in: "36h", want: 36 * time.Hour},
+		{in: "xd", wantErr: true},
+		{in: "soon", wantErr: true},
+		{in: "-5h", wantErr: true},
+		{in: "-3d", wantErr: true},
+		{in: "0d", wantErr: true},
+		{in: "0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAge(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
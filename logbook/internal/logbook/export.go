@@ -0,0 +1,116 @@
+package logbook
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405Z"
+	icsMaxLineOctets  = 75
+)
+
+// icsEscape escapes a TEXT value as described in RFC 5545 section 3.3.11.
+func icsEscape(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(s)
+}
+
+// writeICSLine writes a content line, folding it at 75 octets without
+// splitting a UTF-8 sequence.
+func writeICSLine(w *bufio.Writer, line string) {
+	limit := icsMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// continuation lines start with the folding space
+		limit = icsMaxLineOctets - 1
+	}
+	w.WriteString(line + "\r\n")
+}
+
+// entryUID identifies an entry across exports. Entries can share a second,
+// or even a timestamp, so the text is hashed in as well.
+func entryUID(entry Entry) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(entry.Text))
+	return fmt.Sprintf("entry-%d-%08x@logbook", entry.Timestamp.UnixNano(), hash.Sum32())
+}
+
+// WriteICS writes entries as an iCalendar feed. Entries with a duration
+// become timed events; all others become all-day events on their date.
+func WriteICS(w io.Writer, entries []Entry) error {
+	buf := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(icsDateTimeLayout)
+
+	writeICSLine(buf, "BEGIN:VCALENDAR")
+	writeICSLine(buf, "VERSION:2.0")
+	writeICSLine(buf, "PRODID:-//atabilog//logbook//EN")
+	writeICSLine(buf, "CALSCALE:GREGORIAN")
+	for _, entry := range entries {
+		writeICSLine(buf, "BEGIN:VEVENT")
+		writeICSLine(buf, "UID:"+entryUID(entry))
+		writeICSLine(buf, "DTSTAMP:"+stamp)
+		if entry.Duration > 0 {
+			writeICSLine(buf, "DTSTART:"+entry.Timestamp.UTC().Format(icsDateTimeLayout))
+			writeICSLine(buf, "DTEND:"+entry.Timestamp.Add(entry.Duration).UTC().Format(icsDateTimeLayout))
+		} else {
+			writeICSLine(buf, "DTSTART;VALUE=DATE:"+entry.Timestamp.Format(icsDateLayout))
+			writeICSLine(buf, "DTEND;VALUE=DATE:"+entry.Timestamp.AddDate(0, 0, 1).Format(icsDateLayout))
+		}
+		writeICSLine(buf, "SUMMARY:"+icsEscape(entry.Text))
+		if len(entry.Tags) > 0 {
+			tags := make([]string, len(entry.Tags))
+			for i, tag := range entry.Tags {
+				tags[i] = icsEscape(tag)
+			}
+			writeICSLine(buf, "CATEGORIES:"+strings.Join(tags, ","))
+		}
+		writeICSLine(buf, "END:VEVENT")
+	}
+	writeICSLine(buf, "END:VCALENDAR")
+
+	return buf.Flush()
+}
+
+// Export writes every entry in the given format to outPath, or to stdout
+// when outPath is empty.
+func Export(format string, outPath string) error {
+	if format != "ics" {
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+
+	entries, err := loadEntries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	if outPath == "" {
+		return WriteICS(os.Stdout, entries)
+	}
+
+	file, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", outPath, err)
+	}
+	defer file.Close()
+	if err := WriteICS(file, entries); err != nil {
+		return fmt.Errorf("error writing %s: %w", outPath, err)
+	}
+	fmt.Printf("Saved to: %s\n", outPath)
+	return nil
+}
@@ -0,0 +1,66 @@
+package logbook
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICS(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	entries := []Entry{
+		{Text: "Standup, planning; notes", Timestamp: start, Tags: []string{"work"}, Duration: 15 * time.Minute},
+		{Text: "Read a paper", Timestamp: start, Tags: []string{}},
+		{Text: strings.Repeat("long ", 30), Timestamp: start.Add(time.Hour), Tags: []string{}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, entries); err != nil {
+		t.Fatalf("WriteICS() error = %v", err)
+	}
+	out := buf.String()
+
+	wantLines := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20260302T093000Z\r\n",
+		"DTEND:20260302T094500Z\r\n",
+		"SUMMARY:Standup\\, planning\\; notes\r\n",
+		"CATEGORIES:work\r\n",
+		"DTSTART;VALUE=DATE:20260302\r\n",
+		"DTEND;VALUE=DATE:20260303\r\n",
+		"END:VCALENDAR\r\n",
+	}
+	for _, want := range wantLines {
+		if !strings.Contains(out, want) {
+			t.Errorf("ICS output missing %q", want)
+		}
+	}
+
+	if got := strings.Count(out, "BEGIN:VEVENT"); got != len(entries) {
+		t.Errorf("Got %d events, want %d", got, len(entries))
+	}
+	// the first two entries share a timestamp, but not a UID
+	uids := map[string]bool{}
+	for _, line := range strings.Split(out, "\r\n") {
+		if strings.HasPrefix(line, "UID:") {
+			uids[line] = true
+		}
+	}
+	if len(uids) != len(entries) {
+		t.Errorf("Got %d distinct UIDs, want %d", len(uids), len(entries))
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > icsMaxLineOctets {
+			t.Errorf("Line longer than %d octets: %q", icsMaxLineOctets, line)
+		}
+	}
+}
+
+func TestExportUnsupportedFormat(t *testing.T) {
+	SetEntriesDirectory(t.TempDir())
+
+	if err := Export("csv", ""); err == nil {
+		t.Error("Export() with unsupported format should fail")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -15,6 +16,8 @@ type Entry struct {
 	Text      string    `json:"text"`
 	Timestamp time.Time `json:"timestamp"`
 	Tags      []string  `json:"tags"`
+	// Duration is set for entries recorded with start/stop.
+	Duration time.Duration `json:"duration,omitempty"`
 }
 
 func SetEntriesDirectory(dir string) {
@@ -27,8 +30,14 @@ func saveEntry(entry Entry) error {
 		return fmt.Errorf("error creating JSON: %w", err)
 	}
 
-	filename := fmt.Sprintf("%s/entry_%d.json", entriesDirectory, entry.Timestamp.Unix())
-	err = os.WriteFile(filename, jsonData, 0644)
+	file, filename, err := createEntryFile(entry)
+	if err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	_, err = file.Write(jsonData)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return fmt.Errorf("error writing file: %w", err)
 	}
@@ -37,18 +46,18 @@ func saveEntry(entry Entry) error {
 	return nil
 }
 
-func AddEntry(entry string, tags string) error {
-	var tagSlice []string
-	if tags != "" {
-		tagSlice = strings.Split(tags, ",")
-	} else {
-		tagSlice = []string{}
+func parseTags(tags string) []string {
+	if tags == "" {
+		return []string{}
 	}
+	return strings.Split(tags, ",")
+}
 
+func AddEntry(entry string, tags string) error {
 	newEntry := Entry{
 		Text:      entry,
 		Timestamp: time.Now(),
-		Tags:      tagSlice,
+		Tags:      parseTags(tags),
 	}
 
 	err := saveEntry(newEntry)
@@ -69,7 +78,7 @@ func ListEntries() error {
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
@@ -84,10 +93,140 @@ func ListEntries() error {
 		fmt.Printf("\n=== %s ===\n%s\n", entry.Name(), string(content))
 	}
 
+	archived, err := loadArchivedEntries()
+	if err != nil {
+		fmt.Printf("Error reading archive: %v\n", err)
+		return err
+	}
+	for _, entry := range archived {
+		printEntry(archiveDirName+"/"+entryFileName(entry), entry)
+	}
+
 	return nil
 }
 
+func entryFileName(entry Entry) string {
+	return fmt.Sprintf("entry_%d.json", entry.Timestamp.UnixNano())
+}
+
+// createEntryFile creates the file for a new entry without ever replacing
+// an existing one: a stopped timer is dated back to its start, so two
+// entries can share a timestamp, and the later one gets a numbered suffix.
+func createEntryFile(entry Entry) (*os.File, string, error) {
+	base := strings.TrimSuffix(entryFileName(entry), ".json")
+	for n := 1; ; n++ {
+		name := base + ".json"
+		if n > 1 {
+			name = fmt.Sprintf("%s_%d.json", base, n)
+		}
+		filename := fmt.Sprintf("%s/%s", entriesDirectory, name)
+		file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		return file, filename, err
+	}
+}
+
+func printEntry(name string, entry Entry) {
+	content, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		fmt.Printf("Error formatting %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("\n=== %s ===\n%s\n", name, string(content))
+}
+
+// entryFiles returns the names of the entry files directly in the entries
+// directory, skipping the archive and other hidden or nested files.
+func entryFiles() ([]string, error) {
+	files, err := os.ReadDir(entriesDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+	return names, nil
+}
+
+func readEntryFile(name string) (Entry, error) {
+	var entry Entry
+	content, err := os.ReadFile(filepath.Join(entriesDirectory, name))
+	if err != nil {
+		return entry, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return entry, fmt.Errorf("error parsing %s: %w", name, err)
+	}
+	return entry, nil
+}
+
+// loadEntries reads every entry in the entries directory, including the
+// ones compacted into archive bundles.
+func loadEntries() ([]Entry, error) {
+	names, err := entryFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, name := range names {
+		entry, err := readEntryFile(name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	archived, err := loadArchivedEntries()
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, archived...), nil
+}
+
+func hasAnyTag(entry Entry, tags []string) bool {
+	for _, want := range tags {
+		for _, tag := range entry.Tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SearchByTags prints the entries carrying any of the given tags. Each
+// argument may itself be a comma-separated list.
 func SearchByTags(tags []string) error {
-	fmt.Printf("Searching by tags: %+v", tags)
+	var wanted []string
+	for _, arg := range tags {
+		wanted = append(wanted, parseTags(arg)...)
+	}
+	fmt.Printf("Searching by tags: %+v\n", wanted)
+
+	entries, err := loadEntries()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	found := 0
+	for _, entry := range entries {
+		if hasAnyTag(entry, wanted) {
+			printEntry(entryFileName(entry), entry)
+			found++
+		}
+	}
+	fmt.Printf("\n%d matching entries\n", found)
 	return nil
 }
@@ -130,6 +130,42 @@ func TestSaveEntry(t *testing.T) {
 	}
 }
 
+func TestSaveEntrySameTimestamp(t *testing.T) {
+	tempDir := t.TempDir()
+	SetEntriesDirectory(tempDir)
+
+	// a stopped timer is dated back to its start, which can be the moment
+	// another entry was added
+	timestamp := time.Now()
+	texts := []string{"Added entry", "Timed entry", "Third entry"}
+	for _, text := range texts {
+		if err := saveEntry(Entry{Text: text, Timestamp: timestamp, Tags: []string{}}); err != nil {
+			t.Fatalf("saveEntry() error = %v", err)
+		}
+	}
+
+	names, err := entryFiles()
+	if err != nil {
+		t.Fatalf("entryFiles() error = %v", err)
+	}
+	if len(names) != len(texts) {
+		t.Fatalf("Expected %d files, got %d: %v", len(texts), len(names), names)
+	}
+	saved := map[string]bool{}
+	for _, name := range names {
+		entry, err := readEntryFile(name)
+		if err != nil {
+			t.Fatalf("readEntryFile(%s) error = %v", name, err)
+		}
+		saved[entry.Text] = true
+	}
+	for _, text := range texts {
+		if !saved[text] {
+			t.Errorf("Entry %q was overwritten", text)
+		}
+	}
+}
+
 func TestListEntries(t *testing.T) {
 	tempDir := t.TempDir()
 	SetEntriesDirectory(tempDir)
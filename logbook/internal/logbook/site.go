@@ -0,0 +1,197 @@
+package logbook
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+//go:embed templates
+var siteTemplates embed.FS
+
+type siteLink struct {
+	Name  string
+	Date  string
+	Slug  string
+	Count int
+}
+
+type sitePage struct {
+	Title     string
+	Root      string
+	Generated time.Time
+	Days      []siteLink
+	Tags      []siteLink
+	Entries   []Entry
+}
+
+type searchItem struct {
+	Date string   `json:"date"`
+	Time string   `json:"time"`
+	Text string   `json:"text"`
+	Tags []string `json:"tags"`
+	URL  string   `json:"url"`
+}
+
+// tagSlug turns a tag into a file name that is safe on every platform.
+func tagSlug(tag string) string {
+	slug := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, strings.TrimSpace(tag))
+	if slug == "" {
+		return "tag"
+	}
+	return slug
+}
+
+func parsePage(name string) (*template.Template, error) {
+	return template.New("base.html").
+		Funcs(template.FuncMap{"tagSlug": tagSlug}).
+		ParseFS(siteTemplates, "templates/base.html", "templates/"+name)
+}
+
+func renderPage(tmpl *template.Template, path string, page sitePage) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", filepath.Dir(path), err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer file.Close()
+	if err := tmpl.Execute(file, page); err != nil {
+		return fmt.Errorf("error rendering %s: %w", path, err)
+	}
+	return nil
+}
+
+func copyAsset(outDir, name string) error {
+	data, err := siteTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, name), data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// BuildSite renders a read-only static site of all entries into outDir:
+// an index with a client-side search box, one page per day and one page
+// per tag. Everything it needs is embedded, so it works offline.
+func BuildSite(outDir string) error {
+	entries, err := loadEntries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	byDay := map[string][]Entry{}
+	// tags are grouped by slug so that tags differing only in case or
+	// punctuation share a page instead of overwriting each other's
+	byTag := map[string][]Entry{}
+	tagNames := map[string]string{}
+	var index []searchItem
+	for _, entry := range entries {
+		date := entry.Timestamp.Format("2006-01-02")
+		byDay[date] = append(byDay[date], entry)
+		for _, tag := range entry.Tags {
+			slug := tagSlug(tag)
+			if _, ok := tagNames[slug]; !ok {
+				tagNames[slug] = tag
+			}
+			byTag[slug] = append(byTag[slug], entry)
+		}
+		tags := entry.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		index = append(index, searchItem{
+			Date: date,
+			Time: entry.Timestamp.Format("15:04"),
+			Text: entry.Text,
+			Tags: tags,
+			URL:  "days/" + date + ".html",
+		})
+	}
+
+	generated := time.Now()
+	var days, tags []siteLink
+	for date, dayEntries := range byDay {
+		days = append(days, siteLink{Date: date, Count: len(dayEntries)})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date > days[j].Date })
+	for slug, tagEntries := range byTag {
+		tags = append(tags, siteLink{Name: tagNames[slug], Slug: slug, Count: len(tagEntries)})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	indexTmpl, err := parsePage("index.html")
+	if err != nil {
+		return fmt.Errorf("error parsing templates: %w", err)
+	}
+	entriesTmpl, err := parsePage("entries.html")
+	if err != nil {
+		return fmt.Errorf("error parsing templates: %w", err)
+	}
+
+	if err := renderPage(indexTmpl, filepath.Join(outDir, "index.html"), sitePage{
+		Title:     "All entries",
+		Generated: generated,
+		Days:      days,
+		Tags:      tags,
+	}); err != nil {
+		return err
+	}
+	for _, day := range days {
+		if err := renderPage(entriesTmpl, filepath.Join(outDir, "days", day.Date+".html"), sitePage{
+			Title:     day.Date,
+			Root:      "../",
+			Generated: generated,
+			Entries:   byDay[day.Date],
+		}); err != nil {
+			return err
+		}
+	}
+	for _, tag := range tags {
+		if err := renderPage(entriesTmpl, filepath.Join(outDir, "tags", tag.Slug+".html"), sitePage{
+			Title:     "Tagged " + tag.Name,
+			Root:      "../",
+			Generated: generated,
+			Entries:   byTag[tag.Slug],
+		}); err != nil {
+			return err
+		}
+	}
+
+	// a script rather than a JSON file, since browsers refuse to fetch
+	// local files when the site is opened straight from disk
+	indexJSON, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("error creating search index: %w", err)
+	}
+	script := fmt.Sprintf("window.LOGBOOK_INDEX = %s;\n", indexJSON)
+	if err := os.WriteFile(filepath.Join(outDir, "search-index.js"), []byte(script), 0644); err != nil {
+		return fmt.Errorf("error writing search index: %w", err)
+	}
+	for _, asset := range []string{"style.css", "search.js"} {
+		if err := copyAsset(outDir, asset); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Site with %d entries written to %s\n", len(entries), outDir)
+	return nil
+}
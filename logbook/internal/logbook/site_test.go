@@ -0,0 +1,55 @@
+package logbook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSite(t *testing.T) {
+	tempDir := t.TempDir()
+	SetEntriesDirectory(tempDir)
+
+	day := time.Date(2026, 3, 2, 9, 30, 0, 0, time.Local)
+	testEntries := []Entry{
+		{Text: "Fixed <the> bug", Timestamp: day, Tags: []string{"work", "Go Lang"}},
+		{Text: "Second entry", Timestamp: day.Add(time.Hour), Tags: []string{}},
+	}
+	for _, entry := range testEntries {
+		if err := saveEntry(entry); err != nil {
+			t.Fatalf("Failed to save test entry: %v", err)
+		}
+	}
+
+	outDir := filepath.Join(t.TempDir(), "site")
+	if err := BuildSite(outDir); err != nil {
+		t.Fatalf("BuildSite() error = %v", err)
+	}
+
+	for _, name := range []string{
+		"index.html",
+		"style.css",
+		"search.js",
+		"search-index.js",
+		"days/2026-03-02.html",
+		"tags/work.html",
+		"tags/go-lang.html",
+	} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(outDir, "days", "2026-03-02.html"))
+	if err != nil {
+		t.Fatalf("Failed to read day page: %v", err)
+	}
+	if !strings.Contains(string(page), "Fixed &lt;the&gt; bug") {
+		t.Errorf("Day page does not contain the escaped entry text")
+	}
+	if !strings.Contains(string(page), `href="../tags/go-lang.html"`) {
+		t.Errorf("Day page does not link to the tag page")
+	}
+}
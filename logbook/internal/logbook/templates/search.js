@@ -0,0 +1,42 @@
+// Client-side search over window.LOGBOOK_INDEX, which search-index.js
+// defines so that the site also works when opened from disk.
+(function () {
+    const input = document.getElementById('search');
+    const results = document.getElementById('search-results');
+    const index = window.LOGBOOK_INDEX || [];
+
+    function render(matches) {
+        results.innerHTML = '';
+        matches.forEach(function (item) {
+            const li = document.createElement('li');
+            const link = document.createElement('a');
+            link.href = item.url;
+            link.textContent = item.date + ' ' + item.time;
+            const text = document.createElement('p');
+            text.textContent = item.text;
+            li.appendChild(link);
+            li.appendChild(text);
+            if (item.tags.length > 0) {
+                const tags = document.createElement('span');
+                tags.className = 'tag';
+                tags.textContent = item.tags.join(', ');
+                li.appendChild(tags);
+            }
+            results.appendChild(li);
+        });
+    }
+
+    input.addEventListener('input', function () {
+        const terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+        if (terms.length === 0) {
+            render([]);
+            return;
+        }
+        render(index.filter(function (item) {
+            const haystack = (item.text + ' ' + item.tags.join(' ')).toLowerCase();
+            return terms.every(function (term) {
+                return haystack.includes(term);
+            });
+        }));
+    });
+})();
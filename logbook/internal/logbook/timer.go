@@ -0,0 +1,233 @@
+package logbook
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const untaggedLabel = "(untagged)"
+
+var (
+	ErrTimerRunning    = errors.New("a timer is already running")
+	ErrTimerNotRunning = errors.New("no timer is running")
+)
+
+// Timer is the state of a running `logbook start`, kept in the entries
+// directory until `logbook stop` turns it into an entry.
+type Timer struct {
+	Text  string    `json:"text"`
+	Start time.Time `json:"start"`
+	Tags  []string  `json:"tags"`
+}
+
+// Timesheet holds durations summed per day (YYYY-MM-DD) and per tag.
+type Timesheet struct {
+	From  time.Time
+	To    time.Time
+	ByDay map[string]time.Duration
+	ByTag map[string]time.Duration
+	Total time.Duration
+}
+
+func timerPath() string {
+	return filepath.Join(entriesDirectory, ".timer.json")
+}
+
+func loadTimer() (*Timer, error) {
+	data, err := os.ReadFile(timerPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading timer: %w", err)
+	}
+
+	var timer Timer
+	if err := json.Unmarshal(data, &timer); err != nil {
+		return nil, fmt.Errorf("error parsing timer: %w", err)
+	}
+	return &timer, nil
+}
+
+func StartTimer(text string, tags string) error {
+	running, err := loadTimer()
+	if err != nil {
+		return err
+	}
+	if running != nil {
+		return fmt.Errorf("%w: %s", ErrTimerRunning, running.Text)
+	}
+
+	timer := Timer{Text: text, Start: time.Now(), Tags: parseTags(tags)}
+	jsonData, err := json.MarshalIndent(timer, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error creating JSON: %w", err)
+	}
+	if err := os.WriteFile(timerPath(), jsonData, 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+
+	fmt.Printf("Started: %s at %s\n", text, timer.Start.Format("3:04 PM"))
+	return nil
+}
+
+func StopTimer() error {
+	timer, err := loadTimer()
+	if err != nil {
+		return err
+	}
+	if timer == nil {
+		return ErrTimerNotRunning
+	}
+
+	entry := Entry{
+		Text:      timer.Text,
+		Timestamp: timer.Start,
+		Tags:      timer.Tags,
+		Duration:  time.Since(timer.Start).Round(time.Second),
+	}
+	if err := saveEntry(entry); err != nil {
+		return err
+	}
+	if err := os.Remove(timerPath()); err != nil {
+		return fmt.Errorf("error removing timer: %w", err)
+	}
+
+	fmt.Printf("Stopped: %s after %s\n", entry.Text, entry.Duration)
+	return nil
+}
+
+func TimerStatus() error {
+	timer, err := loadTimer()
+	if err != nil {
+		return err
+	}
+	if timer == nil {
+		fmt.Println("No timer running")
+		return nil
+	}
+
+	fmt.Printf("Running: %s (started %s, %s elapsed)\n",
+		timer.Text, timer.Start.Format("Monday 3:04 PM"), time.Since(timer.Start).Round(time.Second))
+	if len(timer.Tags) > 0 {
+		fmt.Printf("Tags: %v\n", timer.Tags)
+	}
+	return nil
+}
+
+// weekBounds returns the Monday-to-Monday week containing t.
+func weekBounds(t time.Time) (time.Time, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) + 6) % 7
+	from := day.AddDate(0, 0, -offset)
+	return from, from.AddDate(0, 0, 7)
+}
+
+// BuildTimesheet sums the durations of entries started in [from, to).
+// A zero from or to leaves that side of the range open.
+func BuildTimesheet(entries []Entry, from, to time.Time) Timesheet {
+	sheet := Timesheet{
+		From:  from,
+		To:    to,
+		ByDay: map[string]time.Duration{},
+		ByTag: map[string]time.Duration{},
+	}
+
+	for _, entry := range entries {
+		if entry.Duration <= 0 {
+			continue
+		}
+		if !from.IsZero() && entry.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !entry.Timestamp.Before(to) {
+			continue
+		}
+
+		sheet.ByDay[entry.Timestamp.Format("2006-01-02")] += entry.Duration
+		if len(entry.Tags) == 0 {
+			sheet.ByTag[untaggedLabel] += entry.Duration
+		}
+		for _, tag := range entry.Tags {
+			sheet.ByTag[tag] += entry.Duration
+		}
+		sheet.Total += entry.Duration
+	}
+
+	return sheet
+}
+
+func sortedKeys(m map[string]time.Duration) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteCSV writes the timesheet as kind,key,hours rows.
+func (s Timesheet) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"kind", "key", "hours"})
+	for _, day := range sortedKeys(s.ByDay) {
+		writer.Write([]string{"day", day, fmt.Sprintf("%.2f", s.ByDay[day].Hours())})
+	}
+	for _, tag := range sortedKeys(s.ByTag) {
+		writer.Write([]string{"tag", tag, fmt.Sprintf("%.2f", s.ByTag[tag].Hours())})
+	}
+	writer.Write([]string{"total", "", fmt.Sprintf("%.2f", s.Total.Hours())})
+	writer.Flush()
+	return writer.Error()
+}
+
+// PrintTimesheet reports tracked time, limited to the current week when
+// week is set, and additionally writes it to csvPath when one is given.
+func PrintTimesheet(week bool, csvPath string) error {
+	entries, err := loadEntries()
+	if err != nil {
+		return err
+	}
+
+	var from, to time.Time
+	if week {
+		from, to = weekBounds(time.Now())
+	}
+	sheet := BuildTimesheet(entries, from, to)
+
+	if week {
+		fmt.Printf("Timesheet for the week of %s\n", from.Format("Monday, January 2, 2006"))
+	} else {
+		fmt.Println("Timesheet for all entries")
+	}
+	fmt.Println("\nPer day:")
+	for _, day := range sortedKeys(sheet.ByDay) {
+		fmt.Printf("  %-12s %s\n", day, sheet.ByDay[day])
+	}
+	fmt.Println("\nPer tag:")
+	for _, tag := range sortedKeys(sheet.ByTag) {
+		fmt.Printf("  %-12s %s\n", tag, sheet.ByTag[tag])
+	}
+	fmt.Printf("\nTotal: %s\n", sheet.Total)
+
+	if csvPath == "" {
+		return nil
+	}
+	file, err := os.Create(csvPath)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", csvPath, err)
+	}
+	defer file.Close()
+	if err := sheet.WriteCSV(file); err != nil {
+		return fmt.Errorf("error writing %s: %w", csvPath, err)
+	}
+	fmt.Printf("Saved to: %s\n", csvPath)
+	return nil
+}
@@ -0,0 +1,89 @@
+package logbook
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartStopTimer(t *testing.T) {
+	tempDir := t.TempDir()
+	SetEntriesDirectory(tempDir)
+
+	if err := StopTimer(); !errors.Is(err, ErrTimerNotRunning) {
+		t.Fatalf("StopTimer() with no timer error = %v, want %v", err, ErrTimerNotRunning)
+	}
+
+	if err := StartTimer("Write report", "work,writing"); err != nil {
+		t.Fatalf("StartTimer() error = %v", err)
+	}
+	if err := StartTimer("Another task", ""); !errors.Is(err, ErrTimerRunning) {
+		t.Fatalf("second StartTimer() error = %v, want %v", err, ErrTimerRunning)
+	}
+	if err := TimerStatus(); err != nil {
+		t.Fatalf("TimerStatus() error = %v", err)
+	}
+
+	if err := StopTimer(); err != nil {
+		t.Fatalf("StopTimer() error = %v", err)
+	}
+	if _, err := os.Stat(timerPath()); !os.IsNotExist(err) {
+		t.Errorf("timer file still exists after stop")
+	}
+
+	entries, err := loadEntries()
+	if err != nil {
+		t.Fatalf("loadEntries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Text != "Write report" {
+		t.Errorf("Text = %v, want %v", entries[0].Text, "Write report")
+	}
+	if len(entries[0].Tags) != 2 {
+		t.Errorf("Tags length = %v, want 2", len(entries[0].Tags))
+	}
+}
+
+func TestBuildTimesheet(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Text: "a", Timestamp: monday, Tags: []string{"work"}, Duration: time.Hour},
+		{Text: "b", Timestamp: monday.Add(3 * time.Hour), Tags: []string{"work", "review"}, Duration: 30 * time.Minute},
+		{Text: "c", Timestamp: monday.AddDate(0, 0, 1), Tags: []string{}, Duration: 15 * time.Minute},
+		{Text: "no duration", Timestamp: monday, Tags: []string{"work"}},
+		{Text: "last week", Timestamp: monday.AddDate(0, 0, -1), Tags: []string{"work"}, Duration: time.Hour},
+	}
+
+	from, to := weekBounds(monday.AddDate(0, 0, 3))
+	if !from.Equal(monday.Truncate(24 * time.Hour)) {
+		t.Fatalf("weekBounds() from = %v, want %v", from, monday.Truncate(24*time.Hour))
+	}
+
+	sheet := BuildTimesheet(entries, from, to)
+
+	if sheet.Total != 105*time.Minute {
+		t.Errorf("Total = %v, want %v", sheet.Total, 105*time.Minute)
+	}
+	if got := sheet.ByDay["2026-03-02"]; got != 90*time.Minute {
+		t.Errorf("ByDay[2026-03-02] = %v, want %v", got, 90*time.Minute)
+	}
+	if got := sheet.ByTag["work"]; got != 90*time.Minute {
+		t.Errorf("ByTag[work] = %v, want %v", got, 90*time.Minute)
+	}
+	if got := sheet.ByTag[untaggedLabel]; got != 15*time.Minute {
+		t.Errorf("ByTag[%s] = %v, want %v", untaggedLabel, got, 15*time.Minute)
+	}
+
+	var buf bytes.Buffer
+	if err := sheet.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "tag,work,1.50\n") {
+		t.Errorf("CSV missing work row:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// doneCardLimit keeps the done column to the most recently completed tasks
+const doneCardLimit = 10
+
+func countStatus(tasks []Task, status string) int {
+	count := 0
+	for _, task := range tasks {
+		if task.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// wipWarning returns a warning when a status holds more tasks than its
+// WIP limit allows, or an empty string
+func wipWarning(tasks []Task, status string) string {
+	limit, ok := config.WIPLimits[status]
+	if !ok || limit <= 0 {
+		return ""
+	}
+	if count := countStatus(tasks, status); count > limit {
+		return fmt.Sprintf("Warning: %s has %d tasks, over its WIP limit of %d", status, count, limit)
+	}
+	return ""
+}
+
+// moveTask changes a task's status. Moving to the done status completes the
+// task and moving out of it reopens it, so the usual side effects apply.
+// Like done, it will not complete a task with open subtasks or blockers
+// unless forced.
+func moveTask(tasks []Task, id int, status string, force bool) ([]Task, error) {
+	if !config.hasStatus(status) {
+		return nil, fmt.Errorf("unknown status %q: use one of %s", status, strings.Join(config.Statuses, ", "))
+	}
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	if tasks[i].Status == status {
+		return nil, fmt.Errorf("task %d is already in %s", id, status)
+	}
+	if status == config.doneStatus() {
+		if !force {
+			if err := checkCompletable(tasks, id); err != nil {
+				return nil, err
+			}
+		}
+		return completeTask(tasks, id)
+	}
+	if tasks[i].isCompleted() {
+		tasks[i].CompletedAt = nil
+	}
+	tasks[i].Status = status
+	return tasks, nil
+}
+
+// terminalWidth returns the width from $COLUMNS, or a default of 100
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 20 {
+		return n
+	}
+	return 100
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func padRight(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// printBoard renders one column per status side by side. Tasks with a
+// status that is no longer configured get a column of their own at the end.
+func printBoard(tasks []Task) {
+	columns := append([]string{}, config.Statuses...)
+	for _, task := range tasks {
+		found := false
+		for _, c := range columns {
+			if c == task.Status {
+				found = true
+			}
+		}
+		if !found {
+			columns = append(columns, task.Status)
+		}
+	}
+
+	cards := make([][]string, len(columns))
+	headers := make([]string, len(columns))
+	color := useColor()
+	for c, status := range columns {
+		var inColumn []Task
+		for _, task := range tasks {
+			if task.Status == status {
+				inColumn = append(inColumn, task)
+			}
+		}
+		header := fmt.Sprintf("%s (%d", strings.ToUpper(status), len(inColumn))
+		if limit, ok := config.WIPLimits[status]; ok && limit > 0 {
+			header += fmt.Sprintf("/%d", limit)
+			if len(inColumn) > limit {
+				header += " !"
+			}
+		}
+		headers[c] = header + ")"
+
+		if status == config.doneStatus() {
+			sort.SliceStable(inColumn, func(i, j int) bool {
+				return timeLess(inColumn[j].CompletedAt, inColumn[i].CompletedAt)
+			})
+			if len(inColumn) > doneCardLimit {
+				inColumn = inColumn[:doneCardLimit]
+			}
+		} else {
+			sortTasks(inColumn, "priority")
+		}
+		for _, task := range inColumn {
+			card := fmt.Sprintf("%d ", task.ID)
+			if task.Priority != "" {
+				card += "(" + task.Priority + ") "
+			}
+			cards[c] = append(cards[c], card+task.Description)
+		}
+	}
+
+	width := terminalWidth()/len(columns) - 1
+	if width < 12 {
+		width = 12
+	}
+	rows := 0
+	for _, column := range cards {
+		if len(column) > rows {
+			rows = len(column)
+		}
+	}
+
+	var line strings.Builder
+	for _, header := range headers {
+		cell := padRight(truncate(header, width), width)
+		if color && strings.HasSuffix(header, "!)") {
+			cell = colorRed + cell + colorReset
+		}
+		line.WriteString(cell + " ")
+	}
+	fmt.Println(strings.TrimRight(line.String(), " "))
+	fmt.Println(strings.Repeat("-", (width+1)*len(columns)-1))
+	for r := 0; r < rows; r++ {
+		line.Reset()
+		for c := range columns {
+			cell := ""
+			if r < len(cards[c]) {
+				cell = cards[c][r]
+			}
+			line.WriteString(padRight(truncate(cell, width), width) + " ")
+		}
+		fmt.Println(strings.TrimRight(line.String(), " "))
+	}
+}
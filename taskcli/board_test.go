@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	if err := defaultConfig().validate(); err != nil {
+		t.Errorf("defaultConfig().validate() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "one status", cfg: Config{Statuses: []string{"todo"}}},
+		{name: "duplicate status", cfg: Config{Statuses: []string{"todo", "done", "todo"}}},
+		{name: "empty status", cfg: Config{Statuses: []string{"todo", ""}}},
+		{name: "limit for unknown status", cfg: Config{Statuses: []string{"todo", "done"}, WIPLimits: map[string]int{"doing": 2}}},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.validate(); err == nil {
+			t.Errorf("validate() of %s succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestMigrateStatus(t *testing.T) {
+	done, open := true, false
+	tasks := []Task{{Completed: &done}, {Completed: &open}, {}, {Status: "review"}}
+	want := []string{"done", "todo", "todo", "review"}
+	for i := range tasks {
+		migrateStatus(&tasks[i])
+		if tasks[i].Status != want[i] || tasks[i].Completed != nil {
+			t.Errorf("migrateStatus() of task %d = %q, %v, want %q", i, tasks[i].Status, tasks[i].Completed, want[i])
+		}
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Description: "Write report", Status: "todo"},
+		{ID: 2, Description: "Send report", Status: "doing"},
+	}
+
+	tasks, err := moveTask(tasks, 1, "review", false)
+	if err != nil || tasks[0].Status != "review" {
+		t.Fatalf("moveTask(1, review) = %+v, %v", tasks, err)
+	}
+	tasks, err = moveTask(tasks, 1, "done", false)
+	if err != nil || !tasks[0].isCompleted() || tasks[0].CompletedAt == nil {
+		t.Fatalf("moveTask(1, done) = %+v, %v, want it completed", tasks[0], err)
+	}
+	// moving out of done reopens the task
+	tasks, err = moveTask(tasks, 1, "doing", false)
+	if err != nil || tasks[0].Status != "doing" || tasks[0].CompletedAt != nil {
+		t.Errorf("moveTask(1, doing) = %+v, %v, want it reopened", tasks[0], err)
+	}
+
+	for _, tt := range []struct {
+		id      int
+		status  string
+		wantErr string
+	}{
+		{id: 2, status: "doing", wantErr: "already in doing"},
+		{id: 2, status: "blocked", wantErr: "unknown status"},
+		{id: 9, status: "todo", wantErr: "no task with ID 9"},
+	} {
+		if _, err := moveTask(tasks, tt.id, tt.status, false); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("moveTask(%d, %s) error = %v, want one containing %q", tt.id, tt.status, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMoveTaskToDoneChecksDependencies(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Description: "Release", Status: "review"},
+		{ID: 2, Description: "Changelog", Status: "doing", Parent: 1},
+		{ID: 3, Description: "Announce", Status: "todo", BlockedBy: []int{1}},
+	}
+
+	for _, id := range []int{1, 3} {
+		_, err := moveTask(mustCloneTasks(t, tasks), id, "done", false)
+		var open *openDependenciesError
+		if !errors.As(err, &open) {
+			t.Errorf("moveTask(%d, done) error = %v, want open dependencies", id, err)
+		}
+	}
+	if _, err := moveTask(mustCloneTasks(t, tasks), 1, "done", false); err == nil || err.Error() != "task 1 has open subtasks 2" {
+		t.Errorf("moveTask(1, done) error = %v", err)
+	}
+	// other statuses are not completions
+	if _, err := moveTask(mustCloneTasks(t, tasks), 3, "doing", false); err != nil {
+		t.Errorf("moveTask(3, doing) error = %v", err)
+	}
+
+	got, err := moveTask(mustCloneTasks(t, tasks), 1, "done", true)
+	if err != nil || !got[0].isCompleted() {
+		t.Errorf("forced moveTask(1, done) = %+v, %v, want it completed", got[0], err)
+	}
+}
+
+func TestWIPWarning(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+	config = defaultConfig()
+	config.WIPLimits = map[string]int{"doing": 1}
+
+	tasks := []Task{{ID: 1, Status: "doing"}}
+	if got := wipWarning(tasks, "doing"); got != "" {
+		t.Errorf("wipWarning() at the limit = %q, want none", got)
+	}
+	tasks = append(tasks, Task{ID: 2, Status: "doing"})
+	if got := wipWarning(tasks, "doing"); !strings.Contains(got, "doing has 2 tasks, over its WIP limit of 1") {
+		t.Errorf("wipWarning() over the limit = %q", got)
+	}
+	if got := wipWarning(tasks, "review"); got != "" {
+		t.Errorf("wipWarning() without a limit = %q, want none", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{s: "short", width: 10, want: "short"},
+		{s: "exactly", width: 7, want: "exactly"},
+		{s: "too long", width: 5, want: "too …"},
+		{s: "ünïcode", width: 4, want: "ünï…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds the user's taskcli settings, read from config.json in the
+// XDG config directory
+type Config struct {
+	// Statuses are the board columns in order. New tasks get the first
+	// status and the last one counts as completed.
+	Statuses []string `json:"statuses"`
+	// WIPLimits caps the number of tasks per status; exceeding one warns
+	WIPLimits map[string]int `json:"wip_limits,omitempty"`
+	// Filters are named filter expressions, usable by name in list
+	Filters map[string]string `json:"filters,omitempty"`
+	// DueTime is the time of day, HH:MM, tasks are due on their due date
+	DueTime string `json:"due_time,omitempty"`
+	// ReminderLead is how long before that watch reminds of a task
+	ReminderLead string `json:"reminder_lead,omitempty"`
+	// SyncServer is the URL of the taskcli server sync talks to
+	SyncServer string `json:"sync_server,omitempty"`
+	// SyncToken is sent to the sync server, which may require one
+	SyncToken string `json:"sync_token,omitempty"`
+}
+
+var config = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Statuses:     []string{"todo", "doing", "review", "done"},
+		WIPLimits:    map[string]int{},
+		DueTime:      "09:00",
+		ReminderLead: "1h",
+	}
+}
+
+func configFile() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "taskcli", "config.json"), nil
+}
+
+// loadConfig reads the config file over the defaults; a missing file
+// leaves the defaults in place
+func loadConfig() error {
+	path, err := configFile()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	loaded := defaultConfig()
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := loaded.validate(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	config = loaded
+	return nil
+}
+
+func (c Config) validate() error {
+	if len(c.Statuses) < 2 {
+		return fmt.Errorf("at least two statuses are needed, an open and a done one")
+	}
+	seen := map[string]bool{}
+	for _, status := range c.Statuses {
+		if status == "" || seen[status] {
+			return fmt.Errorf("statuses must be unique and not empty")
+		}
+		seen[status] = true
+	}
+	for status := range c.WIPLimits {
+		if !seen[status] {
+			return fmt.Errorf("WIP limit for unknown status %q", status)
+		}
+	}
+	if _, err := parseTimeOfDay(c.DueTime); err != nil {
+		return fmt.Errorf("due_time: %w", err)
+	}
+	if _, err := parseLead(c.ReminderLead); err != nil {
+		return fmt.Errorf("reminder_lead: %w", err)
+	}
+	if c.SyncServer != "" {
+		if err := checkServerURL(c.SyncServer); err != nil {
+			return fmt.Errorf("sync_server: %w", err)
+		}
+	}
+	for _, name := range c.filterNames() {
+		if strings.ContainsAny(name, " \t()\""+filterOperatorChars) {
+			return fmt.Errorf("filter name %q must be a single word", name)
+		}
+		if _, err := parseFilter(c.Filters[name], c); err != nil {
+			return fmt.Errorf("filter %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkServerURL makes sure a sync server is given as an http(s) URL
+func checkServerURL(s string) error {
+	if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", s)
+	}
+	return nil
+}
+
+func (c Config) initialStatus() string {
+	return c.Statuses[0]
+}
+
+func (c Config) doneStatus() string {
+	return c.Statuses[len(c.Statuses)-1]
+}
+
+func (c Config) hasStatus(status string) bool {
+	for _, s := range c.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
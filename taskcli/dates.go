@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// today returns the start of the current day in local time
+func today() time.Time {
+	return startOfDay(time.Now())
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// parseDate understands YYYY-MM-DD, "today", "tomorrow", "yesterday", a
+// weekday name (its next occurrence, today included) and "Nd" for N days
+// from today.
+func parseDate(s string) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	base := today()
+	switch s {
+	case "today":
+		return base, nil
+	case "tomorrow":
+		return base.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return base.AddDate(0, 0, -1), nil
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		name := strings.ToLower(wd.String())
+		if s == name || s == name[:3] {
+			offset := (int(wd) - int(base.Weekday()) + 7) % 7
+			return base.AddDate(0, 0, offset), nil
+		}
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return base.AddDate(0, 0, n), nil
+		}
+	}
+	t, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD, today, tomorrow, a weekday or Nd", s)
+	}
+	return t, nil
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	base := today()
+	// the next occurrence of a weekday, today included
+	weekday := func(wd time.Weekday) time.Time {
+		return base.AddDate(0, 0, (int(wd)-int(base.Weekday())+7)%7)
+	}
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{input: "today", want: base},
+		{input: "Tomorrow", want: base.AddDate(0, 0, 1)},
+		{input: "yesterday", want: base.AddDate(0, 0, -1)},
+		{input: "3d", want: base.AddDate(0, 0, 3)},
+		{input: "0d", want: base},
+		{input: "-2d", want: base.AddDate(0, 0, -2)},
+		{input: "2026-02-28", want: time.Date(2026, 2, 28, 0, 0, 0, 0, time.Local)},
+		{input: " 2024-02-29 ", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local)},
+		{input: "monday", want: weekday(time.Monday)},
+		{input: "fri", want: weekday(time.Friday)},
+		{input: strings.ToLower(base.Weekday().String()), want: base},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseDate(tt.input)
+			if err != nil {
+				t.Fatalf("parseDate(%q) error = %v", tt.input, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q) = %s, want %s", tt.input, got.Format(dateLayout), tt.want.Format(dateLayout))
+			}
+		})
+	}
+}
+
+func TestParseDateErrors(t *testing.T) {
+	for _, input := range []string{"", "someday", "2026-02-30", "2026/10/01", "d", "xd", "mond"} {
+		t.Run(input, func(t *testing.T) {
+			if got, err := parseDate(input); err == nil {
+				t.Errorf("parseDate(%q) = %s, want an error", input, got.Format(dateLayout))
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// childrenOf returns the IDs of the direct subtasks of a task
+func childrenOf(tasks []Task, id int) []int {
+	var children []int
+	for _, task := range tasks {
+		if task.Parent == id {
+			children = append(children, task.ID)
+		}
+	}
+	return children
+}
+
+// blocking returns the IDs of the tasks that a task blocks
+func blocking(tasks []Task, id int) []int {
+	var blocked []int
+	for _, task := range tasks {
+		for _, blocker := range task.BlockedBy {
+			if blocker == id {
+				blocked = append(blocked, task.ID)
+			}
+		}
+	}
+	return blocked
+}
+
+// openDependencies returns the subtasks and blockers of a task that are
+// not completed yet
+func openDependencies(tasks []Task, id int) (children []int, blockers []int) {
+	for _, child := range childrenOf(tasks, id) {
+		if !tasks[findTask(tasks, child)].isCompleted() {
+			children = append(children, child)
+		}
+	}
+	if i := findTask(tasks, id); i >= 0 {
+		for _, blocker := range tasks[i].BlockedBy {
+			if j := findTask(tasks, blocker); j >= 0 && !tasks[j].isCompleted() {
+				blockers = append(blockers, blocker)
+			}
+		}
+	}
+	return children, blockers
+}
+
+// openDependenciesError refuses to complete a task whose subtasks or
+// blockers are still open
+type openDependenciesError struct {
+	id       int
+	children []int
+	blockers []int
+}
+
+func (e *openDependenciesError) Error() string {
+	var open []string
+	if len(e.children) > 0 {
+		open = append(open, "open subtasks "+formatIDs(e.children))
+	}
+	if len(e.blockers) > 0 {
+		open = append(open, "open blockers "+formatIDs(e.blockers))
+	}
+	return fmt.Sprintf("task %d has %s", e.id, strings.Join(open, " and "))
+}
+
+// checkCompletable returns an *openDependenciesError if the task still has
+// open subtasks or blockers
+func checkCompletable(tasks []Task, id int) error {
+	children, blockers := openDependencies(tasks, id)
+	if len(children) > 0 || len(blockers) > 0 {
+		return &openDependenciesError{id: id, children: children, blockers: blockers}
+	}
+	return nil
+}
+
+// dependsOn reports whether task id is blocked by target, directly or
+// through a chain of blockers
+func dependsOn(tasks []Task, id int, target int) bool {
+	seen := map[int]bool{}
+	stack := []int{id}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if current == target {
+			return true
+		}
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		if i := findTask(tasks, current); i >= 0 {
+			stack = append(stack, tasks[i].BlockedBy...)
+		}
+	}
+	return false
+}
+
+// descendsFrom reports whether task id is a subtask of ancestor, directly
+// or further down, or ancestor itself
+func descendsFrom(tasks []Task, id int, ancestor int) bool {
+	seen := map[int]bool{}
+	for current := id; current != 0 && !seen[current]; {
+		if current == ancestor {
+			return true
+		}
+		seen[current] = true
+		i := findTask(tasks, current)
+		if i < 0 {
+			break
+		}
+		current = tasks[i].Parent
+	}
+	return false
+}
+
+// setParent makes task id a subtask of parent, or a top-level task if
+// parent is 0, refusing parents that would make a cycle
+func setParent(tasks []Task, id int, parent int) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	if parent != 0 {
+		if findTask(tasks, parent) < 0 {
+			return nil, fmt.Errorf("no task with ID %d", parent)
+		}
+		if parent == id {
+			return nil, fmt.Errorf("task %d cannot be its own parent", id)
+		}
+		if descendsFrom(tasks, parent, id) {
+			return nil, fmt.Errorf("task %d is a subtask of %d: making it the parent would create a cycle", parent, id)
+		}
+	}
+	tasks[i].Parent = parent
+	return tasks, nil
+}
+
+// addBlocker records that blocker must be done before task id, refusing
+// relations that would make a cycle
+func addBlocker(tasks []Task, id int, blocker int) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	if findTask(tasks, blocker) < 0 {
+		return nil, fmt.Errorf("no task with ID %d", blocker)
+	}
+	if id == blocker {
+		return nil, fmt.Errorf("task %d cannot block itself", id)
+	}
+	for _, existing := range tasks[i].BlockedBy {
+		if existing == blocker {
+			return nil, fmt.Errorf("task %d is already blocked by %d", id, blocker)
+		}
+	}
+	if dependsOn(tasks, blocker, id) {
+		return nil, fmt.Errorf("task %d already depends on %d: adding this would create a cycle", blocker, id)
+	}
+	tasks[i].BlockedBy = append(tasks[i].BlockedBy, blocker)
+	return tasks, nil
+}
+
+// removeBlocker drops a blocked-by relation
+func removeBlocker(tasks []Task, id int, blocker int) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	for j, existing := range tasks[i].BlockedBy {
+		if existing == blocker {
+			tasks[i].BlockedBy = append(tasks[i].BlockedBy[:j], tasks[i].BlockedBy[j+1:]...)
+			return tasks, nil
+		}
+	}
+	return nil, fmt.Errorf("task %d is not blocked by %d", id, blocker)
+}
+
+func formatIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseIDList parses a comma-separated list of task IDs, e.g. "3,5"
+func parseIDList(s string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		id, err := parseID(part)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
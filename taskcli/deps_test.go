@@ -0,0 +1,190 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chainTasks has 1 blocked by 2, blocked by 3, with 4 on its own
+func chainTasks() []Task {
+	return []Task{
+		{ID: 1, Description: "Ship", Status: "todo", BlockedBy: []int{2}},
+		{ID: 2, Description: "Test", Status: "todo", BlockedBy: []int{3}},
+		{ID: 3, Description: "Build", Status: "todo"},
+		{ID: 4, Description: "Unrelated", Status: "todo"},
+	}
+}
+
+func TestDependsOn(t *testing.T) {
+	tests := []struct {
+		name       string
+		id, target int
+		want       bool
+	}{
+		{name: "itself", id: 1, target: 1, want: true},
+		{name: "direct", id: 1, target: 2, want: true},
+		{name: "through a chain", id: 1, target: 3, want: true},
+		{name: "the other way", id: 3, target: 1, want: false},
+		{name: "unrelated", id: 1, target: 4, want: false},
+		{name: "missing task", id: 9, target: 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dependsOn(chainTasks(), tt.id, tt.target); got != tt.want {
+				t.Errorf("dependsOn(%d, %d) = %v, want %v", tt.id, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependsOnStopsOnCycles(t *testing.T) {
+	// a cycle that got into the file some other way must not hang
+	tasks := chainTasks()
+	tasks[2].BlockedBy = []int{1}
+	if dependsOn(tasks, 1, 4) {
+		t.Errorf("dependsOn(1, 4) = true, want false")
+	}
+}
+
+func TestAddBlocker(t *testing.T) {
+	tests := []struct {
+		name        string
+		id, blocker int
+		want        []int
+		wantErr     string
+	}{
+		{name: "new relation", id: 4, blocker: 1, want: []int{1}},
+		{name: "second blocker", id: 1, blocker: 4, want: []int{2, 4}},
+		{name: "shortcut through a chain", id: 1, blocker: 3, want: []int{2, 3}},
+		{name: "itself", id: 1, blocker: 1, wantErr: "cannot block itself"},
+		{name: "already blocked", id: 1, blocker: 2, wantErr: "already blocked"},
+		{name: "direct cycle", id: 2, blocker: 1, wantErr: "cycle"},
+		{name: "cycle through a chain", id: 3, blocker: 1, wantErr: "cycle"},
+		{name: "missing task", id: 9, blocker: 1, wantErr: "no task with ID 9"},
+		{name: "missing blocker", id: 1, blocker: 9, wantErr: "no task with ID 9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addBlocker(chainTasks(), tt.id, tt.blocker)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("addBlocker() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("addBlocker() error = %v", err)
+			}
+			if blockers := got[findTask(got, tt.id)].BlockedBy; !reflect.DeepEqual(blockers, tt.want) {
+				t.Errorf("task %d blocked by %v, want %v", tt.id, blockers, tt.want)
+			}
+		})
+	}
+}
+
+// TestOpenDependencies covers what done and the API refuse to complete a
+// task over
+func TestOpenDependencies(t *testing.T) {
+	tasks := chainTasks()
+	tasks = append(tasks,
+		Task{ID: 5, Description: "Write notes", Status: "todo", Parent: 1},
+		Task{ID: 6, Description: "Tag release", Status: "done", Parent: 1},
+	)
+
+	tests := []struct {
+		name         string
+		tasks        func() []Task
+		id           int
+		wantChildren []int
+		wantBlockers []int
+	}{
+		{
+			name:         "open subtask and blocker",
+			tasks:        func() []Task { return mustCloneTasks(t, tasks) },
+			id:           1,
+			wantChildren: []int{5},
+			wantBlockers: []int{2},
+		},
+		{
+			name: "everything done",
+			tasks: func() []Task {
+				done := mustCloneTasks(t, tasks)
+				done[1].Status = "done"
+				done[4].Status = "done"
+				return done
+			},
+			id: 1,
+		},
+		{
+			// only direct blockers hold a task back
+			name:         "blocker done, its own blocker open",
+			tasks:        func() []Task { done := mustCloneTasks(t, tasks); done[1].Status = "done"; return done },
+			id:           1,
+			wantChildren: []int{5},
+		},
+		{
+			name:         "blocker removed",
+			tasks:        func() []Task { return removeTasks(mustCloneTasks(t, tasks), []int{2}) },
+			id:           1,
+			wantChildren: []int{5},
+		},
+		{name: "free task", tasks: func() []Task { return mustCloneTasks(t, tasks) }, id: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			children, blockers := openDependencies(tt.tasks(), tt.id)
+			if !reflect.DeepEqual(children, tt.wantChildren) || !reflect.DeepEqual(blockers, tt.wantBlockers) {
+				t.Errorf("openDependencies(%d) = %v, %v, want %v, %v", tt.id, children, blockers, tt.wantChildren, tt.wantBlockers)
+			}
+		})
+	}
+}
+
+func TestSetParent(t *testing.T) {
+	// 2 and 3 are subtasks of 1, 4 of 3
+	tree := func() []Task {
+		return []Task{
+			{ID: 1, Description: "Release", Status: "todo"},
+			{ID: 2, Description: "Changelog", Status: "todo", Parent: 1},
+			{ID: 3, Description: "Build", Status: "todo", Parent: 1},
+			{ID: 4, Description: "Sign", Status: "todo", Parent: 3},
+			{ID: 5, Description: "Unrelated", Status: "todo"},
+		}
+	}
+	tests := []struct {
+		name       string
+		id, parent int
+		wantErr    string
+	}{
+		{name: "new parent", id: 5, parent: 4},
+		{name: "move within the tree", id: 4, parent: 2},
+		{name: "top level", id: 4, parent: 0},
+		{name: "itself", id: 3, parent: 3, wantErr: "its own parent"},
+		{name: "own child", id: 3, parent: 4, wantErr: "cycle"},
+		{name: "own grandchild", id: 1, parent: 4, wantErr: "cycle"},
+		{name: "missing parent", id: 1, parent: 9, wantErr: "no task with ID 9"},
+		{name: "missing task", id: 9, parent: 1, wantErr: "no task with ID 9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setParent(tree(), tt.id, tt.parent)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("setParent() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setParent() error = %v", err)
+			}
+			if parent := got[findTask(got, tt.id)].Parent; parent != tt.parent {
+				t.Errorf("task %d has parent %d, want %d", tt.id, parent, tt.parent)
+			}
+		})
+	}
+}
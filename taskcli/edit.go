@@ -0,0 +1,282 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selectionOptions are the filter flags shared by the bulk actions, e.g.
+// `rm --completed --before 30d`
+type selectionOptions struct {
+	completed bool
+	pending   bool
+	before    string
+	project   string
+}
+
+func addSelectionFlags(fs *flag.FlagSet) *selectionOptions {
+	opts := &selectionOptions{}
+	fs.BoolVar(&opts.completed, "completed", false, "Select completed tasks")
+	fs.BoolVar(&opts.pending, "pending", false, "Select pending tasks")
+	fs.StringVar(&opts.before, "before", "", "Select tasks completed (or, if pending, created) before this age or date, e.g. 30d, 2w, 2026-01-31")
+	fs.StringVar(&opts.project, "project", "", "Select tasks in this project")
+	return opts
+}
+
+func (opts *selectionOptions) isSet() bool {
+	return opts.completed || opts.pending || opts.before != "" || opts.project != ""
+}
+
+// parseCutoff turns an age like 30d or 2w, or a date, into a point in time
+func parseCutoff(s string) (time.Time, error) {
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if count, err := strconv.Atoi(n); err == nil && count >= 0 {
+				return today().AddDate(0, 0, -count*days), nil
+			}
+		}
+	}
+	return parseDate(s)
+}
+
+// parseIDSpec parses IDs, ranges and lists such as "3", "3-7" or "3,5,9-11"
+func parseIDSpec(spec string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			id, err := parseID(part)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+			continue
+		}
+		first, err := parseID(from)
+		if err != nil {
+			return nil, err
+		}
+		last, err := parseID(to)
+		if err != nil {
+			return nil, err
+		}
+		if last < first {
+			return nil, fmt.Errorf("invalid range %q: %d is after %d", part, first, last)
+		}
+		for id := first; id <= last; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// selectTasks returns the IDs picked by ID specs and filter flags, in
+// ascending order. Given both, a task must match both. IDs in a range that
+// do not exist are skipped; a single missing ID is an error.
+func selectTasks(tasks []Task, specs []string, opts *selectionOptions) ([]int, error) {
+	if len(specs) == 0 && !opts.isSet() {
+		return nil, fmt.Errorf("no tasks given: pass IDs such as 3 or 3-7, or filters such as --completed")
+	}
+
+	var cutoff time.Time
+	if opts.before != "" {
+		var err error
+		if cutoff, err = parseCutoff(opts.before); err != nil {
+			return nil, err
+		}
+	}
+	matches := func(task Task) bool {
+		if opts.completed && !task.isCompleted() || opts.pending && task.isCompleted() {
+			return false
+		}
+		if opts.project != "" && !containsFold(task.Projects, strings.TrimPrefix(opts.project, "+")) {
+			return false
+		}
+		if opts.before != "" {
+			when := task.CreatedAt
+			if task.isCompleted() {
+				when = task.CompletedAt
+			}
+			if when == nil || !when.Before(cutoff) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var candidates []int
+	if len(specs) == 0 {
+		for _, task := range tasks {
+			candidates = append(candidates, task.ID)
+		}
+	}
+	for _, spec := range specs {
+		ids, err := parseIDSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 1 && findTask(tasks, ids[0]) < 0 {
+			return nil, fmt.Errorf("no task with ID %d", ids[0])
+		}
+		candidates = append(candidates, ids...)
+	}
+
+	seen := map[int]bool{}
+	var selected []int
+	for _, id := range candidates {
+		i := findTask(tasks, id)
+		if i < 0 || seen[id] || !matches(tasks[i]) {
+			continue
+		}
+		seen[id] = true
+		selected = append(selected, id)
+	}
+	sort.Ints(selected)
+	return selected, nil
+}
+
+// editTask replaces a task's description and the fields parsed from it
+func editTask(tasks []Task, id int, description string) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	tasks[i].Description = description
+	parseDescription(&tasks[i])
+	return tasks, nil
+}
+
+// reopenTask moves a completed task back to the first status
+func reopenTask(tasks []Task, id int) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	if !tasks[i].isCompleted() {
+		return nil, fmt.Errorf("task %d is not completed", id)
+	}
+	tasks[i].Status = config.initialStatus()
+	tasks[i].CompletedAt = nil
+	return tasks, nil
+}
+
+// setPriority sets a task's priority; an empty priority clears it
+func setPriority(tasks []Task, id int, priority string) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	tasks[i].Priority = priority
+	return tasks, nil
+}
+
+// removeTasks deletes tasks and any references to them: subtasks of a
+// removed task become top-level tasks and it no longer blocks anything
+func removeTasks(tasks []Task, ids []int) []Task {
+	removed := map[int]bool{}
+	for _, id := range ids {
+		removed[id] = true
+	}
+
+	var kept []Task
+	for _, task := range tasks {
+		if removed[task.ID] {
+			continue
+		}
+		if removed[task.Parent] {
+			task.Parent = 0
+		}
+		var blockers []int
+		for _, blocker := range task.BlockedBy {
+			if !removed[blocker] {
+				blockers = append(blockers, blocker)
+			}
+		}
+		task.BlockedBy = blockers
+		kept = append(kept, task)
+	}
+	if kept == nil {
+		kept = []Task{}
+	}
+	return kept
+}
+
+// archiveFile is the tasks file with .archive before its extension, e.g.
+// tasks.archive.json, so it is stored in the same format
+func archiveFile() string {
+	ext := filepath.Ext(tasksFile)
+	return strings.TrimSuffix(tasksFile, ext) + ".archive" + ext
+}
+
+// readArchive loads the archive file, or no tasks if there is none yet
+func readArchive() ([]Task, error) {
+	archived := []Task{}
+	if _, err := os.Stat(archiveFile()); err != nil {
+		return archived, nil
+	}
+	if err := backupBeforeMigrating(archiveFile()); err != nil {
+		return nil, err
+	}
+	existing, err := readTasksFile(archiveFile(), formatForPath(archiveFile()))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", archiveFile(), err)
+	}
+	return append(archived, existing...), nil
+}
+
+func writeArchive(archived []Task) error {
+	codec, err := codecFor(formatForPath(archiveFile()))
+	if err != nil {
+		return err
+	}
+	var buf strings.Builder
+	if err := codec.encode(&buf, archived); err != nil {
+		return err
+	}
+	return writeFileAtomic(archiveFile(), []byte(buf.String()))
+}
+
+// archiveTasks moves the given tasks from the list to the end of the
+// archive file. The IDs it moved are kept in archived for the journal.
+func archiveTasks(tasks []Task, ids []int) ([]Task, error) {
+	archived, err := readArchive()
+	if err != nil {
+		return nil, err
+	}
+	var moved []int
+	for _, id := range ids {
+		if i := findTask(tasks, id); i >= 0 {
+			archived = append(archived, tasks[i])
+			moved = append(moved, id)
+		}
+	}
+	if err := writeArchive(archived); err != nil {
+		return nil, err
+	}
+	archivedIDs = append(archivedIDs, moved...)
+	return removeTasks(tasks, ids), nil
+}
+
+// unarchiveTasks takes the given tasks back out of the archive file, the
+// last copy of each if an ID is in it more than once
+func unarchiveTasks(ids []int) error {
+	archived, err := readArchive()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		for i := len(archived) - 1; i >= 0; i-- {
+			if archived[i].ID == id {
+				archived = append(archived[:i], archived[i+1:]...)
+				break
+			}
+		}
+	}
+	return writeArchive(archived)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIDSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int
+		wantErr string
+	}{
+		{spec: "3", want: []int{3}},
+		{spec: "3-6", want: []int{3, 4, 5, 6}},
+		{spec: "5-5", want: []int{5}},
+		{spec: "3,5,9-11", want: []int{3, 5, 9, 10, 11}},
+		{spec: "4,4,2-4", want: []int{4, 4, 2, 3, 4}},
+		{spec: "", wantErr: "invalid task ID"},
+		{spec: "0", wantErr: "invalid task ID"},
+		{spec: "x", wantErr: "invalid task ID"},
+		{spec: "3,", wantErr: "invalid task ID"},
+		{spec: "3-", wantErr: "invalid task ID"},
+		{spec: "-3", wantErr: "invalid task ID"},
+		{spec: "3-x", wantErr: "invalid task ID"},
+		{spec: "1-2-3", wantErr: "invalid task ID"},
+		{spec: "7-3", wantErr: "7 is after 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseIDSpec(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseIDSpec(%q) = %v, %v, want an error containing %q", tt.spec, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIDSpec(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCutoff(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{input: "30d", want: today().AddDate(0, 0, -30)},
+		{input: "2w", want: today().AddDate(0, 0, -14)},
+		{input: "0d", want: today()},
+		{input: "2026-01-31", want: time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCutoff(tt.input)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseCutoff(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := parseCutoff("soon"); err == nil {
+		t.Errorf("parseCutoff(soon) succeeded, want an error")
+	}
+}
+
+// selectionTasks has done and pending tasks of two projects, with 4 missing
+func selectionTasks() []Task {
+	long, recent := today().AddDate(0, 0, -60), today().AddDate(0, 0, -2)
+	tasks := []Task{
+		{ID: 1, Description: "Old done +web", Status: "done", CreatedAt: &long, CompletedAt: &long},
+		{ID: 2, Description: "Recently done +web", Status: "done", CreatedAt: &long, CompletedAt: &recent},
+		{ID: 3, Description: "Old pending +api", CreatedAt: &long},
+		{ID: 5, Description: "New pending +web", CreatedAt: &recent},
+		{ID: 6, Description: "Done without dates +api", Status: "done"},
+	}
+	for i := range tasks {
+		parseDescription(&tasks[i])
+	}
+	return tasks
+}
+
+func TestSelectTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []int
+		wantErr string
+	}{
+		{name: "single ID", args: []string{"3"}, want: []int{3}},
+		{name: "range skips missing IDs", args: []string{"2-5"}, want: []int{2, 3, 5}},
+		{name: "duplicates once, in order", args: []string{"5", "3,3", "1-3"}, want: []int{1, 2, 3, 5}},
+		{name: "completed", args: []string{"--completed"}, want: []int{1, 2, 6}},
+		{name: "pending in a project", args: []string{"--pending", "--project", "+web"}, want: []int{5}},
+		{name: "completed before", args: []string{"--completed", "--before", "30d"}, want: []int{1}},
+		{name: "pending created before", args: []string{"--pending", "--before", "30d"}, want: []int{3}},
+		{name: "IDs and filters both apply", args: []string{"--completed", "1-5"}, want: []int{1, 2}},
+		{name: "range with no match", args: []string{"7-9"}, want: nil},
+		{name: "missing single ID", args: []string{"4"}, wantErr: "no task with ID 4"},
+		{name: "bad spec", args: []string{"2-x"}, wantErr: "invalid task ID"},
+		{name: "bad cutoff", args: []string{"--before", "soon"}, wantErr: "invalid date"},
+		{name: "nothing given", args: nil, wantErr: "no tasks given"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("rm", flag.ContinueOnError)
+			opts := addSelectionFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := selectTasks(selectionTasks(), fs.Args(), opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("selectTasks() = %v, %v, want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectTasks(%q) = %v, %v, want %v", tt.args, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Description: "Release"},
+		{ID: 2, Description: "Changelog", Parent: 1, BlockedBy: []int{3}},
+		{ID: 3, Description: "Build", BlockedBy: []int{1}},
+		{ID: 4, Description: "Sign", Parent: 3, BlockedBy: []int{1, 3}},
+	}
+	got := removeTasks(tasks, []int{1, 3, 3, 9})
+	want := []Task{
+		{ID: 2, Description: "Changelog"},
+		{ID: 4, Description: "Sign"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("removeTasks() = %+v, want %+v", got, want)
+	}
+
+	// removing everything leaves an empty list rather than null in the file
+	if got := removeTasks(want, []int{2, 4}); got == nil || len(got) != 0 {
+		t.Errorf("removeTasks() of every task = %#v, want an empty list", got)
+	}
+}
+
+func TestReopenTask(t *testing.T) {
+	done := time.Now()
+	tasks := []Task{{ID: 1, Description: "a", Status: "done", CompletedAt: &done}, {ID: 2, Description: "b"}}
+	got, err := reopenTask(tasks, 1)
+	if err != nil || got[0].isCompleted() || got[0].CompletedAt != nil {
+		t.Errorf("reopenTask(1) = %+v, %v, want it pending", got, err)
+	}
+	if _, err := reopenTask(tasks, 2); err == nil || !strings.Contains(err.Error(), "not completed") {
+		t.Errorf("reopenTask(2) error = %v, want not completed", err)
+	}
+	if _, err := reopenTask(tasks, 3); err == nil {
+		t.Errorf("reopenTask(3) succeeded for a missing task")
+	}
+}
+
+func TestEditTask(t *testing.T) {
+	tasks := []Task{{ID: 1, Description: "Fix login +web"}}
+	parseDescription(&tasks[0])
+	got, err := editTask(tasks, 1, "Fix signup +api @laptop")
+	if err != nil {
+		t.Fatalf("editTask() error = %v", err)
+	}
+	if got[0].Description != "Fix signup +api @laptop" || !reflect.DeepEqual(got[0].Projects, []string{"api"}) || !reflect.DeepEqual(got[0].Contexts, []string{"laptop"}) {
+		t.Errorf("editTask() = %+v", got[0])
+	}
+	if _, err := editTask(tasks, 2, "x"); err == nil {
+		t.Errorf("editTask(2) succeeded for a missing task")
+	}
+}
+
+func TestArchiveTasks(t *testing.T) {
+	useTempTasksFile(t)
+	if got, want := archiveFile(), strings.TrimSuffix(tasksFile, ".json")+".archive.json"; got != want {
+		t.Fatalf("archiveFile() = %q, want %q", got, want)
+	}
+
+	tasks := selectionTasks()
+	tasks, err := archiveTasks(tasks, []int{1, 2})
+	if err != nil {
+		t.Fatalf("archiveTasks() error = %v", err)
+	}
+	// a second archive appends to the first
+	if tasks, err = archiveTasks(tasks, []int{6}); err != nil {
+		t.Fatalf("archiveTasks() error = %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].ID != 3 || tasks[1].ID != 5 {
+		t.Errorf("tasks left = %+v, want 3 and 5", tasks)
+	}
+	archived, err := readTasksFile(archiveFile(), formatForPath(archiveFile()))
+	if err != nil {
+		t.Fatalf("reading the archive: %v", err)
+	}
+	var ids []int
+	for _, task := range archived {
+		ids = append(ids, task.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2, 6}) {
+		t.Errorf("archive holds %v, want [1 2 6]", ids)
+	}
+}
+
+func TestUndoArchive(t *testing.T) {
+	useTempTasksFile(t)
+	archiveIDs := func() []int {
+		t.Helper()
+		archived, err := readArchive()
+		if err != nil {
+			t.Fatalf("readArchive() error = %v", err)
+		}
+		var ids []int
+		for _, task := range archived {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	tasks := selectionTasks()
+	operation = "add"
+	if err := saveTasks(tasks); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := archiveTasks(tasks, []int{1, 2})
+	if err != nil {
+		t.Fatalf("archiveTasks() error = %v", err)
+	}
+	operation = "archive"
+	if err := saveTasks(tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	// undo takes the tasks out of the archive, so stats do not count them twice
+	if tasks, _, err = undoRedo(tasks, journalUndo, 1); err != nil {
+		t.Fatalf("undo error = %v", err)
+	}
+	if got := archiveIDs(); len(got) != 0 || findTask(tasks, 1) < 0 || findTask(tasks, 2) < 0 {
+		t.Errorf("after undo: archive holds %v, tasks %+v, want 1 and 2 back in the list only", got, tasks)
+	}
+
+	if tasks, _, err = undoRedo(tasks, journalRedo, 1); err != nil {
+		t.Fatalf("redo error = %v", err)
+	}
+	if got := archiveIDs(); !reflect.DeepEqual(got, []int{1, 2}) || findTask(tasks, 1) >= 0 {
+		t.Errorf("after redo: archive holds %v, tasks %+v, want 1 and 2 archived again", got, tasks)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Filter expressions select tasks for list, e.g.
+//
+//	status:open and prio>=B and project:visualiser and due<=sunday and not context:waiting
+//
+// Terms are field, operator and value; they combine with and, or, not and
+// parentheses, and has the higher precedence. A bare word names a filter
+// saved in the config file.
+
+// taskFilter reports whether a task matches a filter expression
+type taskFilter func(Task) bool
+
+const (
+	tokenWord = iota
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenEnd
+)
+
+type filterToken struct {
+	kind   int
+	text   string
+	pos    int  // byte offset in the expression
+	quoted bool // a "quoted" word is never a keyword
+}
+
+// filterError points at the token a filter expression went wrong at
+type filterError struct {
+	input string
+	pos   int
+	msg   string
+}
+
+func (e *filterError) Error() string {
+	column := utf8.RuneCountInString(e.input[:e.pos])
+	return fmt.Sprintf("%s\n  %s\n  %s^", e.msg, e.input, strings.Repeat(" ", column))
+}
+
+const filterOperatorChars = ":=!<>"
+
+func tokenizeFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, text: ")", pos: i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(input[i+1:], '"')
+			if end < 0 {
+				return nil, &filterError{input, i, "unterminated quote"}
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: input[i+1 : i+1+end], pos: i, quoted: true})
+			i += end + 2
+		case strings.IndexByte(filterOperatorChars, c) >= 0:
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' && c != ':' && c != '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &filterError{input, i, `unexpected "!": use != or not`}
+			}
+			tokens = append(tokens, filterToken{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t()\""+filterOperatorChars, rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: input[start:i], pos: start})
+		}
+	}
+	tokens = append(tokens, filterToken{kind: tokenEnd, pos: len(input)})
+	return tokens, nil
+}
+
+type filterParser struct {
+	input  string
+	tokens []filterToken
+	next   int
+	config Config
+	saved  []string // saved filters being expanded, to catch cycles
+}
+
+// parseFilter parses a filter expression, expanding the saved filters
+// and checking statuses against the given config
+func parseFilter(input string, c Config) (taskFilter, error) {
+	return parseFilterNested(input, c, nil)
+}
+
+func parseFilterNested(input string, c Config, saved []string) (taskFilter, error) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{input: input, tokens: tokens, config: c, saved: saved}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEnd {
+		return nil, p.errorAt(tok, "expected and, or or the end of the filter, found %q", tok.text)
+	}
+	return filter, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) advance() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEnd {
+		p.next++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given unquoted keyword,
+// consuming it if so
+func (p *filterParser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokenWord && !tok.quoted && strings.EqualFold(tok.text, word) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) errorAt(tok filterToken, format string, args ...any) error {
+	return &filterError{p.input, tok.pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) parseOr() (taskFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t Task) bool { return l(t) || right(t) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (taskFilter, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t Task) bool { return l(t) && right(t) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (taskFilter, error) {
+	if p.keyword("not") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(t Task) bool { return !inner(t) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (taskFilter, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokenOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokenClose {
+			return nil, p.errorAt(closing, "expected ) to close the ( at column %d", tok.pos+1)
+		}
+		p.advance()
+		return inner, nil
+	case tokenEnd:
+		return nil, p.errorAt(tok, "expected a filter term at the end of the filter")
+	case tokenWord:
+		if !tok.quoted && (strings.EqualFold(tok.text, "and") || strings.EqualFold(tok.text, "or")) {
+			return nil, p.errorAt(tok, "expected a filter term before %q", tok.text)
+		}
+		if p.peek().kind == tokenOperator {
+			op := p.advance()
+			value := p.advance()
+			if value.kind != tokenWord {
+				return nil, p.errorAt(value, "expected a value after %s%s", tok.text, op.text)
+			}
+			return p.term(tok, op, value)
+		}
+		return p.savedFilter(tok)
+	}
+	return nil, p.errorAt(tok, "expected a filter term, found %q", tok.text)
+}
+
+func (p *filterParser) savedFilter(tok filterToken) (taskFilter, error) {
+	expr, ok := p.config.Filters[tok.text]
+	if !ok {
+		msg := fmt.Sprintf("unknown saved filter %q; terms look like field:value", tok.text)
+		if names := p.config.filterNames(); len(names) > 0 {
+			msg += " (saved filters: " + strings.Join(names, ", ") + ")"
+		}
+		return nil, p.errorAt(tok, "%s", msg)
+	}
+	for _, name := range p.saved {
+		if name == tok.text {
+			return nil, p.errorAt(tok, "saved filter %q refers to itself", tok.text)
+		}
+	}
+	filter, err := parseFilterNested(expr, p.config, append(p.saved, tok.text))
+	if err != nil {
+		// report the mistake inside the saved filter, where it can be fixed
+		if fe, ok := err.(*filterError); ok {
+			fe.msg = fmt.Sprintf("in saved filter %q: %s", tok.text, fe.msg)
+		}
+		return nil, err
+	}
+	return filter, nil
+}
+
+// term builds the filter for one field, operator and value
+func (p *filterParser) term(field, op, value filterToken) (taskFilter, error) {
+	name := strings.ToLower(field.text)
+	switch name {
+	case "status", "project", "context", "tag", "text":
+		if op.text != ":" && op.text != "=" && op.text != "!=" {
+			return nil, p.errorAt(op, "%s can only be compared with :, = or !=", name)
+		}
+	}
+	negate := op.text == "!="
+	is := func(match func(Task) bool) taskFilter {
+		return func(t Task) bool { return match(t) != negate }
+	}
+
+	switch name {
+	case "status":
+		switch v := value.text; {
+		case v == "open":
+			return is(func(t Task) bool { return !t.isCompleted() }), nil
+		case v == "done" || v == "completed":
+			return is(Task.isCompleted), nil
+		case p.config.hasStatus(v):
+			return is(func(t Task) bool { return t.Status == v }), nil
+		}
+		return nil, p.errorAt(value, "unknown status %q: use open, done or one of %s",
+			value.text, strings.Join(p.config.Statuses, ", "))
+	case "project", "context", "tag":
+		v := strings.TrimLeft(value.text, "+@#")
+		values := map[string]func(Task) []string{
+			"project": func(t Task) []string { return t.Projects },
+			"context": func(t Task) []string { return t.Contexts },
+			"tag":     func(t Task) []string { return t.Tags },
+		}[name]
+		if v == "none" {
+			return is(func(t Task) bool { return len(values(t)) == 0 }), nil
+		}
+		return is(func(t Task) bool { return containsFold(values(t), v) }), nil
+	case "text":
+		v := strings.ToLower(value.text)
+		return is(func(t Task) bool { return strings.Contains(strings.ToLower(t.Description), v) }), nil
+	case "prio", "priority":
+		if value.text == "none" {
+			return p.noneTerm(op, func(t Task) bool { return t.Priority == "" })
+		}
+		want, err := parsePriority(value.text)
+		if err != nil {
+			return nil, p.errorAt(value, "%v", err)
+		}
+		// a higher priority has a lower rank
+		return func(t Task) bool {
+			return compareOp(op.text, priorityRank(want)-priorityRank(t.Priority))
+		}, nil
+	case "id":
+		want, err := strconv.Atoi(value.text)
+		if err != nil {
+			return nil, p.errorAt(value, "invalid task ID %q", value.text)
+		}
+		return func(t Task) bool { return compareOp(op.text, t.ID-want) }, nil
+	case "due", "created", "completed":
+		date := map[string]func(Task) *time.Time{
+			"due":       func(t Task) *time.Time { return t.Due },
+			"created":   func(t Task) *time.Time { return t.CreatedAt },
+			"completed": func(t Task) *time.Time { return t.CompletedAt },
+		}[name]
+		if value.text == "none" {
+			return p.noneTerm(op, func(t Task) bool { return date(t) == nil })
+		}
+		want, err := parseDate(value.text)
+		if err != nil {
+			return nil, p.errorAt(value, "%v", err)
+		}
+		return func(t Task) bool {
+			d := date(t)
+			return d != nil && compareOp(op.text, startOfDay(*d).Compare(want))
+		}, nil
+	}
+	return nil, p.errorAt(field, "unknown field %q: use status, prio, project, context, tag, text, id, due, created or completed", field.text)
+}
+
+// noneTerm handles field:none and field!=none for optional fields
+func (p *filterParser) noneTerm(op filterToken, isNone func(Task) bool) (taskFilter, error) {
+	switch op.text {
+	case ":", "=":
+		return isNone, nil
+	case "!=":
+		return func(t Task) bool { return !isNone(t) }, nil
+	}
+	return nil, p.errorAt(op, "none can only be compared with :, = or !=")
+}
+
+// compareOp applies an operator to the result of comparing a task's value
+// with the one in the filter
+func compareOp(op string, cmp int) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+func (c Config) filterNames() []string {
+	var names []string
+	for name := range c.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string // kind text@pos, kinds as w, o, (, )
+		wantErr string
+		wantPos int
+	}{
+		{
+			name:  "terms and keywords",
+			input: "status:open and prio>=B",
+			want:  []string{"w status@0", "o :@6", "w open@7", "w and@12", "w prio@16", "o >=@20", "w B@22"},
+		},
+		{
+			name:  "every operator",
+			input: "a:1 b=2 c!=3 d<4 e<=5 f>6 g>=7",
+			want: []string{
+				"w a@0", "o :@1", "w 1@2", "w b@4", "o =@5", "w 2@6", "w c@8", "o !=@9", "w 3@11",
+				"w d@13", "o <@14", "w 4@15", "w e@17", "o <=@18", "w 5@20", "w f@22", "o >@23", "w 6@24",
+				"w g@26", "o >=@27", "w 7@29",
+			},
+		},
+		{
+			name:  "parentheses without spaces",
+			input: "(project:a or tag:b)and not(id:3)",
+			want: []string{
+				"( (@0", "w project@1", "o :@8", "w a@9", "w or@11", "w tag@14", "o :@17", "w b@18", ") )@19",
+				"w and@20", "w not@24", "( (@27", "w id@28", "o :@30", "w 3@31", ") )@32",
+			},
+		},
+		{
+			name:  "quoted words",
+			input: `text:"call bob: (urgent)" or "and"`,
+			want:  []string{"w text@0", "o :@4", `w "call bob: (urgent)"@5`, "w or@26", `w "and"@29`},
+		},
+		{
+			name:  "tabs and dates",
+			input: "due<=2026-10-20\tor\tdue:none",
+			want:  []string{"w due@0", "o <=@3", "w 2026-10-20@5", "w or@16", "w due@19", "o :@22", "w none@23"},
+		},
+		{name: "empty", input: "", want: nil},
+		{name: "unterminated quote", input: `text:"call bob`, wantErr: "unterminated quote", wantPos: 5},
+		{name: "bare !", input: "not ! tag:x", wantErr: `unexpected "!"`, wantPos: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenizeFilter(tt.input)
+			if tt.wantErr != "" {
+				fe, ok := err.(*filterError)
+				if !ok || !strings.Contains(fe.msg, tt.wantErr) || fe.pos != tt.wantPos {
+					t.Fatalf("tokenizeFilter() error = %#v, want %q at %d", err, tt.wantErr, tt.wantPos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tokenizeFilter() error = %v", err)
+			}
+			if end := tokens[len(tokens)-1]; end.kind != tokenEnd || end.pos != len(tt.input) {
+				t.Errorf("last token = %+v, want the end at %d", end, len(tt.input))
+			}
+			var got []string
+			for _, tok := range tokens[:len(tokens)-1] {
+				kind := map[int]string{tokenWord: "w", tokenOperator: "o", tokenOpen: "(", tokenClose: ")"}[tok.kind]
+				text := tok.text
+				if tok.quoted {
+					text = `"` + text + `"`
+				}
+				got = append(got, fmt.Sprintf("%s %s@%d", kind, text, tok.pos))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeFilter(%q) =\n%q\nwant\n%q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// filterTasks are the tasks the parse tests below run their filters on
+func filterTasks() []Task {
+	tasks := []Task{
+		{ID: 1, Description: "Fix login +web #bug", Status: "todo", Priority: "A"},
+		{ID: 2, Description: "Write docs +web", Status: "doing", Priority: "C"},
+		{ID: 3, Description: "Call Bob @phone", Status: "done", Priority: "B"},
+		{ID: 4, Description: "Fix and deploy +api #bug", Status: "review"},
+		{ID: 5, Description: "Plan offsite +api @office", Status: "todo", Priority: "B"},
+	}
+	for i := range tasks {
+		parseDescription(&tasks[i])
+	}
+	return tasks
+}
+
+func filterConfig() Config {
+	c := defaultConfig()
+	c.Filters = map[string]string{
+		"urgent":    "prio>=B and status:open",
+		"web":       "project:web",
+		"urgentweb": "urgent and web",
+		"self":      "prio:A or self",
+		"ping":      "status:open and pong",
+		"pong":      "tag:bug or ping",
+		"broken":    "prio:A and due:whenever",
+	}
+	return c
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []int
+	}{
+		{name: "one term", input: "project:web", want: []int{1, 2}},
+		{name: "and binds tighter than or", input: "project:api or project:web and prio:A", want: []int{1, 4, 5}},
+		{name: "and binds tighter than or on the left", input: "project:web and prio:A or project:api", want: []int{1, 4, 5}},
+		{name: "parentheses", input: "(project:api or project:web) and prio:A", want: []int{1}},
+		{name: "not binds tightest", input: "not project:web and tag:bug", want: []int{4}},
+		{name: "not of a group", input: "not (project:web or tag:bug)", want: []int{3, 5}},
+		{name: "double not", input: "not not status:done", want: []int{3}},
+		{name: "keywords in any case", input: "tag:bug AND Not project:web", want: []int{4}},
+		{name: "quoted keyword is a value", input: `text:"and"`, want: []int{4}},
+		{name: "status open", input: "status:open", want: []int{1, 2, 4, 5}},
+		{name: "status by name", input: "status!=todo and status!=done", want: []int{2, 4}},
+		{name: "priority compares by rank", input: "prio>=B", want: []int{1, 3, 5}},
+		{name: "priority below", input: "prio<B", want: []int{2, 4}},
+		{name: "priority none", input: "prio:none", want: []int{4}},
+		{name: "id range", input: "id>=2 and id<4", want: []int{2, 3}},
+		{name: "context without sigil", input: "context:phone or context:@office", want: []int{3, 5}},
+		{name: "saved filter", input: "urgent", want: []int{1, 5}},
+		{name: "saved filters inside saved filters", input: "urgentweb", want: []int{1}},
+		{name: "saved filter in an expression", input: "not urgent and status:open", want: []int{2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseFilter(tt.input, filterConfig())
+			if err != nil {
+				t.Fatalf("parseFilter(%q) error = %v", tt.input, err)
+			}
+			var got []int
+			for _, task := range filterTasks() {
+				if filter(task) {
+					got = append(got, task.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFilter(%q) matches %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseFilterErrors checks each error's message and the column its
+// caret points at
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantMsg    string
+		wantInput  string // the expression the caret is under, if not input
+		wantColumn int
+	}{
+		{name: "unknown field", input: "status:open and colour:red", wantMsg: `unknown field "colour"`, wantColumn: 16},
+		{name: "unknown status", input: "status:blocked", wantMsg: `unknown status "blocked"`, wantColumn: 7},
+		{name: "missing value", input: "tag:x and prio>=", wantMsg: "expected a value after prio>=", wantColumn: 16},
+		{name: "value is a parenthesis", input: "tag:(x)", wantMsg: "expected a value after tag:", wantColumn: 4},
+		{name: "operator for a text field", input: "tag<x", wantMsg: "tag can only be compared with", wantColumn: 3},
+		{name: "none with an order", input: "due<none", wantMsg: "none can only be compared with", wantColumn: 3},
+		{name: "invalid priority", input: "prio:Z", wantMsg: "priority", wantColumn: 5},
+		{name: "invalid ID", input: "id:x", wantMsg: `invalid task ID "x"`, wantColumn: 3},
+		{name: "dangling and", input: "tag:x and", wantMsg: "expected a filter term at the end", wantColumn: 9},
+		{name: "leading or", input: "or tag:x", wantMsg: `expected a filter term before "or"`, wantColumn: 0},
+		{name: "two terms without and", input: "tag:x tag:y", wantMsg: "expected and, or or the end", wantColumn: 6},
+		{name: "unclosed parenthesis", input: "(tag:x or tag:y", wantMsg: "expected ) to close the ( at column 1", wantColumn: 15},
+		{name: "stray parenthesis", input: "tag:x)", wantMsg: `found ")"`, wantColumn: 5},
+		{name: "empty parentheses", input: "tag:x and ()", wantMsg: `expected a filter term, found ")"`, wantColumn: 11},
+		{name: "unknown saved filter", input: "tag:x or later", wantMsg: `unknown saved filter "later"`, wantColumn: 9},
+		{name: "column counts characters", input: `text:"café über" or colour:x`, wantMsg: `unknown field "colour"`, wantColumn: 20},
+		{name: "unterminated quote", input: `tag:x or text:"x`, wantMsg: "unterminated quote", wantColumn: 14},
+		{
+			name:       "saved filter that refers to itself",
+			input:      "tag:x or self",
+			wantMsg:    `in saved filter "self": saved filter "self" refers to itself`,
+			wantInput:  "prio:A or self",
+			wantColumn: 10,
+		},
+		{
+			name:       "cycle through two saved filters",
+			input:      "ping",
+			wantMsg:    `in saved filter "ping": in saved filter "pong": saved filter "ping" refers to itself`,
+			wantInput:  "tag:bug or ping",
+			wantColumn: 11,
+		},
+		{
+			name:       "mistake inside a saved filter",
+			input:      "tag:x and broken",
+			wantMsg:    `in saved filter "broken": `,
+			wantInput:  "prio:A and due:whenever",
+			wantColumn: 15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFilter(tt.input, filterConfig())
+			if err == nil {
+				t.Fatalf("parseFilter(%q) succeeded, want an error", tt.input)
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("error has %d lines, want message, filter and caret:\n%s", len(lines), err)
+			}
+			if !strings.Contains(lines[0], tt.wantMsg) {
+				t.Errorf("message = %q, want one containing %q", lines[0], tt.wantMsg)
+			}
+			wantInput := tt.input
+			if tt.wantInput != "" {
+				wantInput = tt.wantInput
+			}
+			if lines[1] != "  "+wantInput {
+				t.Errorf("filter line = %q, want %q", lines[1], "  "+wantInput)
+			}
+			if column := strings.Index(lines[2], "^") - 2; column != tt.wantColumn || strings.TrimSpace(lines[2]) != "^" {
+				t.Errorf("caret at column %d, want %d:\n%s", column, tt.wantColumn, err)
+			}
+		})
+	}
+}
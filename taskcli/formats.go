@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// taskCodec converts a whole task list to and from one file format
+type taskCodec interface {
+	decode(r io.Reader) ([]Task, error)
+	encode(w io.Writer, tasks []Task) error
+}
+
+var codecs = map[string]taskCodec{
+	"json":    jsonCodec{},
+	"todotxt": todoTxtCodec{},
+	"ics":     icsCodec{},
+}
+
+// formatExtensions maps file extensions to codec names; anything else is JSON
+var formatExtensions = map[string]string{
+	".txt": "todotxt",
+	".ics": "ics",
+}
+
+// formatForPath picks the format name for a file from its extension
+func formatForPath(path string) string {
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return "json"
+}
+
+func codecFor(format string) (taskCodec, error) {
+	codec, ok := codecs[format]
+	if !ok {
+		names := make([]string, 0, len(codecs))
+		for name := range codecs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown format %q: use one of %s", format, strings.Join(names, ", "))
+	}
+	return codec, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) decode(r io.Reader) ([]Task, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tasks, _, err := decodeTasksDocument(data)
+	return tasks, err
+}
+
+func (jsonCodec) encode(w io.Writer, tasks []Task) error {
+	data, err := encodeTasksDocument(tasks)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// mergeTasks adds imported tasks to the list. An imported task replaces the
+// existing task with the same ID; tasks without an ID get a new one.
+func mergeTasks(tasks []Task, imported []Task) ([]Task, int, int) {
+	added, updated := 0, 0
+	for _, task := range imported {
+		if task.ID != 0 {
+			if i := findTask(tasks, task.ID); i >= 0 {
+				tasks[i] = task
+				updated++
+				continue
+			}
+		} else {
+			task.ID = nextID(tasks)
+		}
+		tasks = append(tasks, task)
+		added++
+	}
+	return tasks, added, updated
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "tasks.json", want: "json"},
+		{path: "todo.txt", want: "todotxt"},
+		{path: "/home/me/TODO.TXT", want: "todotxt"},
+		{path: "tasks", want: "json"},
+		{path: "notes.md", want: "json"},
+	}
+
+	for _, tt := range tests {
+		if got := formatForPath(tt.path); got != tt.want {
+			t.Errorf("formatForPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tasks := []Task{fullTask(), doneTask()}
+	got, encoded := roundTrip(t, jsonCodec{}, tasks)
+	checkSameTasks(t, got, tasks, encoded)
+
+	if got, err := (jsonCodec{}).decode(strings.NewReader(" \n")); err != nil || len(got) != 0 {
+		t.Errorf("decode() of an empty file = %v, %v, want no tasks", got, err)
+	}
+}
+
+func TestMergeTasks(t *testing.T) {
+	useTempTasksFile(t)
+	tasks := []Task{{ID: 1, Description: "a"}, {ID: 3, Description: "b"}}
+	imported := []Task{
+		{ID: 3, Description: "b changed"},
+		{ID: 5, Description: "new with its ID"},
+		{Description: "new without an ID"},
+	}
+	got, added, updated := mergeTasks(tasks, imported)
+	if added != 2 || updated != 1 {
+		t.Errorf("mergeTasks() added, updated = %d, %d, want 2, 1", added, updated)
+	}
+	want := map[int]string{1: "a", 3: "b changed", 5: "new with its ID", 6: "new without an ID"}
+	gotByID := map[int]string{}
+	for _, task := range got {
+		gotByID[task.ID] = task.Description
+	}
+	if !reflect.DeepEqual(gotByID, want) {
+		t.Errorf("mergeTasks() = %v, want %v", gotByID, want)
+	}
+}
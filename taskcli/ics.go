@@ -0,0 +1,615 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) support: each task is a VTODO. The standard
+// properties carry what calendar apps show; X-TASKCLI- properties keep the
+// rest, so exporting and importing again gives the same tasks. Tasks are
+// matched by UID on import, and relations between tasks use UIDs too.
+
+const (
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405Z"
+	icsLocalLayout    = "20060102T150405"
+	// lines are folded to at most 75 octets, continuation lines start
+	// with a space
+	icsMaxLineOctets = 75
+)
+
+// icsPriorities maps priorities to the 1 (highest) to 9 scale of PRIORITY
+var icsPriorities = map[string]int{"A": 1, "B": 3, "C": 5, "D": 7}
+
+var icsFrequencies = map[string]string{"day": "DAILY", "week": "WEEKLY", "month": "MONTHLY", "year": "YEARLY"}
+
+type icsCodec struct{}
+
+// taskUID returns the UID a task is exported with. Tasks created by
+// taskcli have none until they are imported, so one is derived from the ID
+// and creation time, which stays the same from one export to the next.
+func taskUID(task Task) string {
+	if task.UID != "" {
+		return task.UID
+	}
+	return derivedUID(task)
+}
+
+func derivedUID(task Task) string {
+	var created int64
+	if task.CreatedAt != nil {
+		created = task.CreatedAt.Unix()
+	}
+	return fmt.Sprintf("taskcli-%d-%d", task.ID, created)
+}
+
+func findTaskByUID(tasks []Task, uid string) int {
+	for i, task := range tasks {
+		if taskUID(task) == uid {
+			return i
+		}
+	}
+	return -1
+}
+
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func icsUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// writeICSLine writes one content line, folding it without splitting a
+// UTF-8 sequence
+func writeICSLine(w *bufio.Writer, line string) {
+	limit := icsMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// the leading space counts towards the next line's length
+		limit = icsMaxLineOctets - 1
+	}
+	w.WriteString(line + "\r\n")
+}
+
+// recurrenceRRULE converts a recurrence rule to an RRULE value
+func recurrenceRRULE(rule string) (string, error) {
+	r, err := parseRecurrence(rule)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{"FREQ=" + icsFrequencies[r.unit]}
+	if r.interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.interval))
+	}
+	if r.weekday != nil {
+		parts = append(parts, "BYDAY="+strings.ToUpper(r.weekday.String()[:2]))
+	}
+	if r.monthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.monthDay))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// rruleRecurrence converts the RRULEs taskcli can express back to a rule;
+// anything else gives an error
+func rruleRecurrence(value string) (string, error) {
+	fields := map[string]string{}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		fields[strings.ToUpper(key)] = strings.ToUpper(val)
+	}
+	interval := 1
+	if fields["INTERVAL"] != "" {
+		n, err := strconv.Atoi(fields["INTERVAL"])
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("unsupported RRULE %q", value)
+		}
+		interval = n
+	}
+	unit := ""
+	for u, freq := range icsFrequencies {
+		if fields["FREQ"] == freq {
+			unit = u
+		}
+	}
+	switch {
+	case unit == "" || fields["COUNT"] != "" || fields["UNTIL"] != "":
+	case fields["BYDAY"] != "" && unit == "week" && interval == 1:
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if fields["BYDAY"] == strings.ToUpper(wd.String()[:2]) {
+				return "every " + strings.ToLower(wd.String()), nil
+			}
+		}
+	case fields["BYMONTHDAY"] != "" && unit == "month" && interval == 1:
+		if day, err := strconv.Atoi(fields["BYMONTHDAY"]); err == nil && day >= 1 && day <= 31 {
+			return "monthly on the " + ordinal(day), nil
+		}
+	case fields["BYDAY"] == "" && fields["BYMONTHDAY"] == "":
+		if interval == 1 {
+			return "every " + unit, nil
+		}
+		return fmt.Sprintf("every %d %ss", interval, unit), nil
+	}
+	return "", fmt.Errorf("unsupported RRULE %q", value)
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+func (icsCodec) encode(w io.Writer, tasks []Task) error {
+	uids := map[int]string{}
+	for _, task := range tasks {
+		uids[task.ID] = taskUID(task)
+	}
+	stamp := time.Now().UTC().Format(icsDateTimeLayout)
+
+	buf := bufio.NewWriter(w)
+	writeICSLine(buf, "BEGIN:VCALENDAR")
+	writeICSLine(buf, "VERSION:2.0")
+	writeICSLine(buf, "PRODID:-//taskcli//taskcli//EN")
+	for _, task := range tasks {
+		line := func(name, value string) { writeICSLine(buf, name+":"+value) }
+		line("BEGIN", "VTODO")
+		line("UID", icsEscape(uids[task.ID]))
+		line("DTSTAMP", stamp)
+		line("SUMMARY", icsEscape(task.Description))
+
+		switch {
+		case task.isCompleted():
+			line("STATUS", "COMPLETED")
+		case task.Status == config.initialStatus():
+			line("STATUS", "NEEDS-ACTION")
+		default:
+			line("STATUS", "IN-PROCESS")
+			line("X-TASKCLI-STATUS", icsEscape(task.Status))
+		}
+		if p, ok := icsPriorities[task.Priority]; ok {
+			line("PRIORITY", strconv.Itoa(p))
+		}
+		if task.Due != nil {
+			due := task.Due.Format(icsDateLayout)
+			line("DUE;VALUE=DATE", due)
+			if task.Recur != "" {
+				// a recurrence is anchored at DTSTART
+				line("DTSTART;VALUE=DATE", due)
+			}
+		}
+		if task.CreatedAt != nil {
+			line("CREATED", task.CreatedAt.UTC().Format(icsDateTimeLayout))
+		}
+		if task.CompletedAt != nil {
+			line("COMPLETED", task.CompletedAt.UTC().Format(icsDateTimeLayout))
+		}
+		if task.ModifiedAt != nil {
+			line("LAST-MODIFIED", task.ModifiedAt.UTC().Format(icsDateTimeLayout))
+		}
+		if task.Recur != "" {
+			if rrule, err := recurrenceRRULE(task.Recur); err == nil {
+				line("RRULE", rrule)
+			}
+			line("X-TASKCLI-RECUR", icsEscape(task.Recur))
+		}
+		if task.RecurDay != 0 {
+			line("X-TASKCLI-RECUR-DAY", strconv.Itoa(task.RecurDay))
+		}
+		var categories []string
+		for _, p := range task.Projects {
+			categories = append(categories, icsEscape("+"+p))
+		}
+		for _, c := range task.Contexts {
+			categories = append(categories, icsEscape("@"+c))
+		}
+		for _, t := range task.Tags {
+			categories = append(categories, icsEscape("#"+t))
+		}
+		if len(categories) > 0 {
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		if uid, ok := uids[task.Parent]; ok {
+			line("RELATED-TO;RELTYPE=PARENT", icsEscape(uid))
+		}
+		for _, id := range task.BlockedBy {
+			if uid, ok := uids[id]; ok {
+				line("RELATED-TO;RELTYPE=DEPENDS-ON", icsEscape(uid))
+			}
+		}
+		if len(task.TimeEntries) > 0 {
+			line("X-TASKCLI-TIME", formatTimeEntries(task.TimeEntries))
+		}
+		if task.Notes != "" {
+			line("DESCRIPTION", icsEscape(task.Notes))
+		}
+		for _, a := range task.Annotations {
+			line("X-TASKCLI-NOTE;X-TIME="+a.Time.UTC().Format(icsDateTimeLayout), icsEscape(a.Text))
+		}
+		if task.Source != nil {
+			line("X-TASKCLI-SOURCE", formatSource(*task.Source))
+		}
+		line("X-TASKCLI-ID", strconv.Itoa(task.ID))
+		line("END", "VTODO")
+	}
+	writeICSLine(buf, "END:VCALENDAR")
+	return buf.Flush()
+}
+
+// icsProperty is one unfolded content line
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// readICSProperties unfolds and splits the content lines of a file
+func readICSProperties(r io.Reader) ([]icsProperty, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var props []icsProperty
+	for n, line := range lines {
+		// the value starts at the first colon outside a quoted parameter
+		colon, quoted := -1, false
+		for i, c := range line {
+			if c == '"' {
+				quoted = !quoted
+			} else if c == ':' && !quoted {
+				colon = i
+				break
+			}
+		}
+		if colon < 0 {
+			return nil, fmt.Errorf("content line %d has no value: %q", n+1, line)
+		}
+		parts := strings.Split(line[:colon], ";")
+		prop := icsProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+		for _, param := range parts[1:] {
+			key, val, _ := strings.Cut(param, "=")
+			prop.params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+		}
+		props = append(props, prop)
+	}
+	return props, nil
+}
+
+// parseICSTime reads a DATE or DATE-TIME value as a local time
+func parseICSTime(prop icsProperty) (*time.Time, error) {
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	var t time.Time
+	var err error
+	switch {
+	case len(prop.value) == len(icsDateLayout):
+		t, err = time.ParseInLocation(icsDateLayout, prop.value, time.Local)
+	case strings.HasSuffix(prop.value, "Z"):
+		t, err = time.Parse(icsDateTimeLayout, prop.value)
+	default:
+		t, err = time.ParseInLocation(icsLocalLayout, prop.value, loc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", prop.name, prop.value)
+	}
+	t = t.Local()
+	return &t, nil
+}
+
+// icsTodo is a decoded VTODO with its relations still given as UIDs
+type icsTodo struct {
+	task     Task
+	parent   string
+	blockers []string
+	props    map[string]bool // names of the properties it carries
+}
+
+func parseICSTodos(r io.Reader) ([]icsTodo, error) {
+	props, err := readICSProperties(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var todos []icsTodo
+	var todo *icsTodo
+	depth := 0 // nesting inside the VTODO, for VALARMs and the like
+	for _, prop := range props {
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VTODO") && todo == nil:
+			todo = &icsTodo{props: map[string]bool{}}
+			continue
+		case todo == nil:
+			continue
+		case prop.name == "BEGIN":
+			depth++
+			continue
+		case prop.name == "END" && depth > 0:
+			depth--
+			continue
+		case depth > 0:
+			continue
+		case prop.name == "END":
+			if todo.task.UID == "" {
+				return nil, fmt.Errorf("VTODO %q has no UID", todo.task.Description)
+			}
+			finishICSTodo(&todo.task)
+			todos = append(todos, *todo)
+			todo = nil
+			continue
+		}
+		if err := applyICSProperty(todo, prop); err != nil {
+			return nil, err
+		}
+		todo.props[prop.name] = true
+	}
+	if todo != nil {
+		return nil, fmt.Errorf("VTODO %q is not closed", todo.task.Description)
+	}
+	return todos, nil
+}
+
+func applyICSProperty(todo *icsTodo, prop icsProperty) error {
+	task := &todo.task
+	var err error
+	switch prop.name {
+	case "UID":
+		task.UID = icsUnescape(prop.value)
+	case "SUMMARY":
+		task.Description = strings.Join(strings.Fields(icsUnescape(prop.value)), " ")
+	case "STATUS":
+		switch strings.ToUpper(prop.value) {
+		case "COMPLETED", "CANCELLED":
+			task.Status = config.doneStatus()
+		case "IN-PROCESS":
+			// the first status after the initial one, unless
+			// X-TASKCLI-STATUS names the exact one
+			if task.Status == "" {
+				task.Status = config.Statuses[1]
+			}
+		}
+	case "X-TASKCLI-STATUS":
+		if status := icsUnescape(prop.value); config.hasStatus(status) {
+			task.Status = status
+		}
+	case "PRIORITY":
+		n, err := strconv.Atoi(prop.value)
+		if err != nil || n < 0 || n > 9 {
+			return fmt.Errorf("invalid PRIORITY %q", prop.value)
+		}
+		// 1-2 is A, 3-4 B and so on; 0 means none
+		if n > 0 {
+			task.Priority = priorities[min((n-1)/2, len(priorities)-1)]
+		}
+	case "DUE":
+		if task.Due, err = parseICSTime(prop); err == nil {
+			due := startOfDay(*task.Due)
+			task.Due = &due
+		}
+	case "CREATED":
+		task.CreatedAt, err = parseICSTime(prop)
+	case "COMPLETED":
+		task.CompletedAt, err = parseICSTime(prop)
+	case "LAST-MODIFIED":
+		task.ModifiedAt, err = parseICSTime(prop)
+	case "RRULE":
+		// X-TASKCLI-RECUR has the rule as it was typed
+		if task.Recur == "" {
+			task.Recur, _ = rruleRecurrence(prop.value)
+		}
+	case "X-TASKCLI-RECUR":
+		task.Recur = icsUnescape(prop.value)
+	case "X-TASKCLI-RECUR-DAY":
+		day, err := strconv.Atoi(prop.value)
+		if err != nil || day < 1 || day > 31 {
+			return fmt.Errorf("invalid X-TASKCLI-RECUR-DAY %q", prop.value)
+		}
+		task.RecurDay = day
+	case "RELATED-TO":
+		switch strings.ToUpper(prop.params["RELTYPE"]) {
+		case "", "PARENT":
+			todo.parent = icsUnescape(prop.value)
+		case "DEPENDS-ON":
+			todo.blockers = append(todo.blockers, icsUnescape(prop.value))
+		}
+	case "X-TASKCLI-TIME":
+		task.TimeEntries, err = parseTimeEntries(prop.value)
+	case "DESCRIPTION":
+		task.Notes = icsUnescape(prop.value)
+	case "X-TASKCLI-NOTE":
+		var at *time.Time
+		if at, err = parseICSTime(icsProperty{name: "X-TIME", value: prop.params["X-TIME"]}); err == nil {
+			task.Annotations = append(task.Annotations, annotation{Time: *at, Text: icsUnescape(prop.value)})
+		}
+	case "X-TASKCLI-SOURCE":
+		task.Source, err = parseSource(prop.value)
+	case "X-TASKCLI-ID":
+		task.ID, err = parseID(prop.value)
+	}
+	return err
+}
+
+// finishICSTodo fills in what the properties left open
+func finishICSTodo(task *Task) {
+	if task.Status == "" {
+		task.Status = config.initialStatus()
+	}
+	parseDescription(task)
+}
+
+// stampCompletion gives a completed task that came without a completion
+// time the given one, or the current time
+func stampCompletion(task *Task, completed *time.Time) {
+	if !task.isCompleted() || task.CompletedAt != nil {
+		return
+	}
+	if completed == nil {
+		now := time.Now().Truncate(time.Second)
+		completed = &now
+	}
+	task.CompletedAt = completed
+}
+
+// decode reads the tasks of a calendar file on their own. Tasks from other
+// apps, which have no taskcli ID, are numbered after the highest one, and
+// relations are resolved within the file.
+func (icsCodec) decode(r io.Reader) ([]Task, error) {
+	todos, err := parseICSTodos(r)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]Task, len(todos))
+	for i, todo := range todos {
+		tasks[i] = todo.task
+	}
+	assignIDs(tasks)
+	for i := range tasks {
+		dropDerivedUID(&tasks[i])
+		stampCompletion(&tasks[i], nil)
+	}
+	for i, todo := range todos {
+		resolveICSRelations(&tasks[i], todo, tasks)
+	}
+	return tasks, nil
+}
+
+// dropDerivedUID clears a UID that taskUID would give the task anyway, so
+// that tasks exported by taskcli come back unchanged
+func dropDerivedUID(task *Task) {
+	if task.UID == derivedUID(*task) {
+		task.UID = ""
+	}
+}
+
+func resolveICSRelations(task *Task, todo icsTodo, tasks []Task) {
+	task.Parent, task.BlockedBy = 0, nil
+	if i := findTaskByUID(tasks, todo.parent); todo.parent != "" && i >= 0 {
+		task.Parent = tasks[i].ID
+	}
+	for _, uid := range todo.blockers {
+		if i := findTaskByUID(tasks, uid); i >= 0 {
+			task.BlockedBy = append(task.BlockedBy, tasks[i].ID)
+		}
+	}
+	sort.Ints(task.BlockedBy)
+}
+
+// icsFields lists the task fields each property sets, in the order they
+// are merged
+var icsFields = []struct {
+	props []string
+	set   func(task *Task, from Task)
+}{
+	{[]string{"SUMMARY"}, func(task *Task, from Task) { task.Description = from.Description }},
+	{[]string{"STATUS", "X-TASKCLI-STATUS"}, func(task *Task, from Task) {
+		task.Status = from.Status
+		if !task.isCompleted() {
+			task.CompletedAt = nil
+		}
+	}},
+	{[]string{"PRIORITY"}, func(task *Task, from Task) { task.Priority = from.Priority }},
+	{[]string{"DUE"}, func(task *Task, from Task) { task.Due = from.Due }},
+	{[]string{"CREATED"}, func(task *Task, from Task) { task.CreatedAt = from.CreatedAt }},
+	{[]string{"COMPLETED"}, func(task *Task, from Task) { task.CompletedAt = from.CompletedAt }},
+	{[]string{"LAST-MODIFIED"}, func(task *Task, from Task) { task.ModifiedAt = from.ModifiedAt }},
+	{[]string{"RRULE", "X-TASKCLI-RECUR"}, func(task *Task, from Task) { task.Recur = from.Recur }},
+	{[]string{"X-TASKCLI-RECUR-DAY"}, func(task *Task, from Task) { task.RecurDay = from.RecurDay }},
+	{[]string{"X-TASKCLI-TIME"}, func(task *Task, from Task) { task.TimeEntries = from.TimeEntries }},
+	{[]string{"DESCRIPTION"}, func(task *Task, from Task) { task.Notes = from.Notes }},
+	{[]string{"X-TASKCLI-NOTE"}, func(task *Task, from Task) { task.Annotations = from.Annotations }},
+	{[]string{"X-TASKCLI-SOURCE"}, func(task *Task, from Task) { task.Source = from.Source }},
+}
+
+// mergeICSTodo updates a task with the fields its VTODO carries. Calendar
+// apps drop the X-TASKCLI- properties and relations they do not know, so a
+// field without its property is kept rather than cleared.
+func mergeICSTodo(task Task, todo icsTodo) Task {
+	for _, field := range icsFields {
+		for _, prop := range field.props {
+			if todo.props[prop] {
+				field.set(&task, todo.task)
+				break
+			}
+		}
+	}
+	parseDescription(&task)
+	return task
+}
+
+// importICS creates or updates tasks from a calendar file by UID. A task
+// whose UID is already known keeps its ID and the fields the calendar file
+// leaves out. A new task keeps its X-TASKCLI-ID if no task has that ID, or
+// gets the next free one.
+func importICS(tasks []Task, r io.Reader) ([]Task, int, int, error) {
+	todos, err := parseICSTodos(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	added, updated := 0, 0
+	var indexes []int
+	merged := make([]bool, len(todos))
+	for n, todo := range todos {
+		task := todo.task
+		if i := findTaskByUID(tasks, task.UID); i >= 0 {
+			task = mergeICSTodo(tasks[i], todo)
+			stampCompletion(&task, tasks[i].CompletedAt)
+			tasks[i] = task
+			indexes = append(indexes, i)
+			merged[n] = true
+			updated++
+			continue
+		}
+		if task.ID == 0 || findTask(tasks, task.ID) >= 0 {
+			task.ID = nextID(tasks)
+		}
+		dropDerivedUID(&task)
+		stampCompletion(&task, nil)
+		tasks = append(tasks, task)
+		indexes = append(indexes, len(tasks)-1)
+		added++
+	}
+	// relations can point at tasks anywhere in the list, now that every
+	// imported task is in it. An updated task without any keeps its own.
+	for n, todo := range todos {
+		if !todo.props["RELATED-TO"] && merged[n] {
+			continue
+		}
+		resolveICSRelations(&tasks[indexes[n]], todo, tasks)
+	}
+	return tasks, added, updated, nil
+}
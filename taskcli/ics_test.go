@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// relatedTasks is fullTask with the parent and blockers it points at, as
+// relations only survive within one file
+func relatedTasks() []Task {
+	return []Task{
+		{ID: 3, Description: "Parser rewrite", Status: config.initialStatus(), CreatedAt: localTime(2026, 9, 30, 8, 0)},
+		{ID: 4, Description: "Lexer", Status: config.doneStatus(), CompletedAt: localTime(2026, 10, 1, 12, 0)},
+		{ID: 5, Description: "Grammar; with, separators \\ in it", Status: config.initialStatus(), UID: "other-app-5"},
+		fullTask(),
+	}
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks []Task
+	}{
+		{name: "all fields with relations", tasks: relatedTasks()},
+		{name: "completed with priority", tasks: []Task{doneTask()}},
+		{name: "recurring", tasks: []Task{{ID: 1, Description: "Backup", Status: config.initialStatus(), Due: localTime(2026, 10, 31, 0, 0), Recur: "every month"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoded := roundTrip(t, icsCodec{}, tt.tasks)
+			checkSameTasks(t, got, tt.tasks, encoded)
+		})
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "Buy milk", want: "Buy milk"},
+		{name: "separators", text: "a,b;c", want: `a\,b\;c`},
+		{name: "backslash", text: `C:\tmp\n`, want: `C:\\tmp\\n`},
+		{name: "newline", text: "one\ntwo", want: `one\ntwo`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := icsEscape(tt.text); got != tt.want {
+				t.Errorf("icsEscape(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if got := icsUnescape(tt.want); got != tt.text {
+				t.Errorf("icsUnescape(%q) = %q, want %q", tt.want, got, tt.text)
+			}
+		})
+	}
+}
+
+func TestWriteICSLineFolds(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "short", line: "SUMMARY:Buy milk"},
+		{name: "exactly the limit", line: "SUMMARY:" + strings.Repeat("x", icsMaxLineOctets-len("SUMMARY:"))},
+		{name: "ascii", line: "DESCRIPTION:" + strings.Repeat("0123456789", 30)},
+		{name: "multibyte", line: "SUMMARY:" + strings.Repeat("häßlich ünd 日本語 ", 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeICSLine(w, tt.line)
+			w.Flush()
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+			for i, line := range lines {
+				if len(line) > icsMaxLineOctets {
+					t.Errorf("line %d is %d octets long: %q", i, len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d does not start with a space: %q", i, line)
+				}
+			}
+			if len(tt.line) <= icsMaxLineOctets && len(lines) != 1 {
+				t.Errorf("folded a line that fits: %q", buf.String())
+			}
+
+			props, err := readICSProperties(&buf)
+			if err != nil {
+				t.Fatalf("readICSProperties() error = %v", err)
+			}
+			name, value, _ := strings.Cut(tt.line, ":")
+			if len(props) != 1 || props[0].name != name || props[0].value != value {
+				t.Errorf("readICSProperties() = %+v, want %s with value %q", props, name, value)
+			}
+		})
+	}
+}
+
+func TestImportICSMatchesUID(t *testing.T) {
+	useTempTasksFile(t)
+	existing := relatedTasks()
+	var exported bytes.Buffer
+	if err := (icsCodec{}).encode(&exported, existing); err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+
+	// another app edits one task and adds one of its own
+	calendar := strings.Replace(exported.String(), "SUMMARY:Lexer", "SUMMARY:Lexer and tokens", 1)
+	calendar = strings.Replace(calendar, "END:VCALENDAR", strings.Join([]string{
+		"BEGIN:VTODO",
+		"UID:phone-42",
+		"SUMMARY:Call the plumber",
+		"PRIORITY:1",
+		"RELATED-TO;RELTYPE=DEPENDS-ON:other-app-5",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n"), 1)
+
+	// the local list has gained task 9 since
+	local := append(mustCloneTasks(t, existing), Task{ID: 9, Description: "Local only", Status: config.initialStatus()})
+	tasks, added, updated, err := importICS(local, strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("importICS() error = %v", err)
+	}
+	if added != 1 || updated != 4 {
+		t.Errorf("importICS() added, updated = %d, %d, want 1, 4", added, updated)
+	}
+	if len(tasks) != 6 {
+		t.Fatalf("importICS() gave %d tasks, want 6", len(tasks))
+	}
+
+	byID := map[int]Task{}
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	if got := byID[4].Description; got != "Lexer and tokens" {
+		t.Errorf("task 4 = %q, want the edited description", got)
+	}
+	if got := byID[5]; got.UID != "other-app-5" || got.Description != existing[2].Description {
+		t.Errorf("task 5 = %+v, want it unchanged", got)
+	}
+	if _, ok := byID[9]; !ok {
+		t.Errorf("the task only in the local list was dropped")
+	}
+	// tasks that came back keep their ID, new ones are numbered after the
+	// highest
+	if !sameTask(byID[7], existing[3]) {
+		t.Errorf("task 7 = %+v, want %+v", byID[7], existing[3])
+	}
+	phone := byID[10]
+	if phone.UID != "phone-42" || phone.Priority != "A" || len(phone.BlockedBy) != 1 || phone.BlockedBy[0] != 5 {
+		t.Errorf("new task = %+v, want ID 10 with its UID, priority and blocker", phone)
+	}
+
+	// importing the same file again only updates
+	_, added, updated, err = importICS(tasks, strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("importICS() error = %v", err)
+	}
+	if added != 0 || updated != 5 {
+		t.Errorf("importing again added, updated = %d, %d, want 0, 5", added, updated)
+	}
+}
+
+func TestImportICSKeepsWhatTheCalendarDrops(t *testing.T) {
+	useTempTasksFile(t)
+	existing := relatedTasks()
+	// a calendar app that knows no X- properties or relations saved the
+	// task back, and brought back a task removed here along with a new one
+	// whose ID is taken
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VTODO",
+		"UID:calendar-app-1234@example.com",
+		"SUMMARY:Review the parser +visualiser",
+		"STATUS:COMPLETED",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:taskcli-12-0",
+		"SUMMARY:Removed here",
+		"X-TASKCLI-ID:12",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:phone-1",
+		"SUMMARY:Taken ID",
+		"X-TASKCLI-ID:3",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+	tasks, added, updated, err := importICS(mustCloneTasks(t, existing), strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("importICS() error = %v", err)
+	}
+	if added != 2 || updated != 1 {
+		t.Errorf("importICS() added, updated = %d, %d, want 2, 1", added, updated)
+	}
+
+	want := existing[3]
+	got := tasks[findTask(tasks, want.ID)]
+	if got.Description != "Review the parser +visualiser" || !got.isCompleted() || got.CompletedAt == nil {
+		t.Errorf("task 7 = %+v, want the new description and completed", got)
+	}
+	if got.Notes != want.Notes || got.Parent != want.Parent || len(got.BlockedBy) != 2 ||
+		len(got.Annotations) != 1 || len(got.TimeEntries) != 1 || got.Source == nil ||
+		got.Priority != want.Priority || got.Recur != want.Recur || !sameTime(got.Due, want.Due) {
+		t.Errorf("task 7 = %+v, want the fields the calendar left out kept from %+v", got, want)
+	}
+	if len(got.Links) == 0 || len(got.Projects) != 1 || got.Projects[0] != "visualiser" {
+		t.Errorf("task 7 links, projects = %v, %v, want them from the new description and kept notes", got.Links, got.Projects)
+	}
+
+	if i := findTask(tasks, 12); i < 0 || tasks[i].Description != "Removed here" || tasks[i].UID != "" {
+		t.Errorf("the task with free X-TASKCLI-ID 12 did not keep it: %+v", tasks)
+	}
+	if i := findTask(tasks, 13); i < 0 || tasks[i].UID != "phone-1" {
+		t.Errorf("the task whose ID 3 is taken should get 13: %+v", tasks)
+	}
+}
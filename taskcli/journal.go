@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Every save appends what changed to a journal next to the tasks file, one
+// JSON object per line. The journal is never rewritten: undo and redo are
+// recorded as entries of their own, so the full history stays readable
+// with `taskcli log`.
+
+const (
+	journalChange = "change"
+	journalUndo   = "undo"
+	journalRedo   = "redo"
+)
+
+// taskChange is one task before and after an operation. Before is nil for
+// an added task and After is nil for a removed one.
+type taskChange struct {
+	ID     int   `json:"id"`
+	Before *Task `json:"before,omitempty"`
+	After  *Task `json:"after,omitempty"`
+}
+
+type journalEntry struct {
+	Seq     int          `json:"seq"`
+	Time    time.Time    `json:"time"`
+	Kind    string       `json:"kind"`          // change, undo or redo
+	Op      string       `json:"op"`            // the command that was run
+	Ref     int          `json:"ref,omitempty"` // seq of the entry an undo or redo applies to
+	Changes []taskChange `json:"changes"`
+	// Archived lists the removed tasks that were moved to the archive file
+	Archived []int `json:"archived,omitempty"`
+}
+
+var (
+	// loadedTasks is the task list as last loaded or saved, so that
+	// saveTasks can journal what changed since
+	loadedTasks []Task
+	// operation describes the running command in the journal
+	operation string
+	// archivedIDs are the tasks the running command moved to the archive
+	// file, so that undo can take them out again
+	archivedIDs []int
+)
+
+func journalFile() string {
+	return tasksFile + ".journal"
+}
+
+// cloneTasks deep-copies tasks so later edits do not change the snapshot
+func cloneTasks(tasks []Task) ([]Task, error) {
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		return nil, err
+	}
+	var clone []Task
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+func sameTask(a, b Task) bool {
+	aData, _ := json.Marshal(a)
+	bData, _ := json.Marshal(b)
+	return bytes.Equal(aData, bData)
+}
+
+// stampModified sets the modification time of the tasks changed between
+// two lists, unless the change set it itself, as sync does when it takes
+// the version of another machine. A task that only got a UID, as sync
+// gives one, was not edited.
+func stampModified(before, after []Task) {
+	now := time.Now().Truncate(time.Second)
+	for i := range after {
+		j := findTask(before, after[i].ID)
+		if j < 0 {
+			if after[i].ModifiedAt == nil {
+				after[i].ModifiedAt = &now
+			}
+			continue
+		}
+		old := before[j]
+		if old.UID == "" {
+			old.UID = after[i].UID
+		}
+		if sameTask(old, after[i]) || !sameTime(old.ModifiedAt, after[i].ModifiedAt) {
+			continue
+		}
+		after[i].ModifiedAt = &now
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// diffTasks lists the tasks added, removed or modified between two lists
+func diffTasks(before, after []Task) []taskChange {
+	var changes []taskChange
+	for _, task := range after {
+		task := task
+		i := findTask(before, task.ID)
+		if i < 0 {
+			changes = append(changes, taskChange{ID: task.ID, After: &task})
+		} else if !sameTask(before[i], task) {
+			old := before[i]
+			changes = append(changes, taskChange{ID: task.ID, Before: &old, After: &task})
+		}
+	}
+	for _, task := range before {
+		task := task
+		if findTask(after, task.ID) < 0 {
+			changes = append(changes, taskChange{ID: task.ID, Before: &task})
+		}
+	}
+	return changes
+}
+
+func readJournal() ([]journalEntry, error) {
+	file, err := os.Open(journalFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("corrupt journal entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// lastJournalSeq reads the seq of the last journal entry from the end of
+// the file, so that a save does not read the whole history
+func lastJournalSeq() (int, error) {
+	file, err := os.Open(journalFile())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// read back from the end until the chunk holds the whole last line
+	size := info.Size()
+	for chunk := int64(4096); ; chunk *= 2 {
+		start := max(size-chunk, 0)
+		data := make([]byte, size-start)
+		if _, err := file.ReadAt(data, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		data = bytes.TrimRight(data, " \t\r\n")
+		nl := bytes.LastIndexByte(data, '\n')
+		if nl < 0 && start > 0 {
+			continue
+		}
+		if len(data) == 0 {
+			return 0, nil
+		}
+		var last struct {
+			Seq int `json:"seq"`
+		}
+		if err := json.Unmarshal(data[nl+1:], &last); err != nil {
+			return 0, fmt.Errorf("corrupt last journal entry: %w", err)
+		}
+		return last.Seq, nil
+	}
+}
+
+func appendJournal(entry journalEntry) error {
+	seq, err := lastJournalSeq()
+	if err != nil {
+		return err
+	}
+	entry.Seq = seq + 1
+	entry.Time = time.Now()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(journalFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// journalStacks replays the journal into the entries that can be undone
+// and redone, most recent last. A new change clears the redo stack.
+func journalStacks(entries []journalEntry) (undo []journalEntry, redo []journalEntry) {
+	bySeq := map[int]journalEntry{}
+	for _, entry := range entries {
+		bySeq[entry.Seq] = entry
+		switch entry.Kind {
+		case journalChange:
+			undo = append(undo, entry)
+			redo = nil
+		case journalUndo:
+			if len(undo) > 0 {
+				redo = append(redo, undo[len(undo)-1])
+				undo = undo[:len(undo)-1]
+			}
+		case journalRedo:
+			if len(redo) > 0 {
+				undo = append(undo, redo[len(redo)-1])
+				redo = redo[:len(redo)-1]
+			}
+		}
+	}
+	return undo, redo
+}
+
+// putTask replaces the task with the same ID or inserts it in ID order
+func putTask(tasks []Task, task Task) []Task {
+	if i := findTask(tasks, task.ID); i >= 0 {
+		tasks[i] = task
+		return tasks
+	}
+	at := len(tasks)
+	for i, existing := range tasks {
+		if existing.ID > task.ID {
+			at = i
+			break
+		}
+	}
+	tasks = append(tasks, Task{})
+	copy(tasks[at+1:], tasks[at:])
+	tasks[at] = task
+	return tasks
+}
+
+func dropTask(tasks []Task, id int) []Task {
+	if i := findTask(tasks, id); i >= 0 {
+		return append(tasks[:i], tasks[i+1:]...)
+	}
+	return tasks
+}
+
+// applyChanges moves each changed task to its before state when reverting,
+// or to its after state otherwise
+func applyChanges(tasks []Task, changes []taskChange, revert bool) []Task {
+	for _, change := range changes {
+		target := change.After
+		if revert {
+			target = change.Before
+		}
+		if target == nil {
+			tasks = dropTask(tasks, change.ID)
+		} else {
+			// entries written before statuses existed still hold the flag
+			task := *target
+			migrateStatus(&task)
+			tasks = putTask(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// applyArchived takes the tasks an entry archived out of the archive file
+// when reverting it, or archives them again otherwise, so undoing an
+// archive does not leave the tasks in both files
+func applyArchived(entry journalEntry, revert bool) error {
+	if len(entry.Archived) == 0 {
+		return nil
+	}
+	if revert {
+		return unarchiveTasks(entry.Archived)
+	}
+	archived, err := readArchive()
+	if err != nil {
+		return err
+	}
+	for _, id := range entry.Archived {
+		for _, change := range entry.Changes {
+			if change.ID == id && change.Before != nil && change.After == nil {
+				archived = append(archived, *change.Before)
+			}
+		}
+	}
+	return writeArchive(archived)
+}
+
+// undoRedo reverts (undo) or reapplies (redo) the last n operations,
+// returning the updated tasks and the operations it applied to
+func undoRedo(tasks []Task, kind string, n int) ([]Task, []string, error) {
+	entries, err := readJournal()
+	if err != nil {
+		return nil, nil, err
+	}
+	undoStack, redoStack := journalStacks(entries)
+	stack := undoStack
+	if kind == journalRedo {
+		stack = redoStack
+	}
+	if len(stack) == 0 {
+		return nil, nil, fmt.Errorf("nothing to %s", kind)
+	}
+	if n > len(stack) {
+		return nil, nil, fmt.Errorf("only %d operation(s) to %s", len(stack), kind)
+	}
+
+	var ops []string
+	for k := 0; k < n; k++ {
+		entry := stack[len(stack)-1-k]
+		if err := applyArchived(entry, kind == journalUndo); err != nil {
+			return nil, nil, err
+		}
+		tasks = applyChanges(tasks, entry.Changes, kind == journalUndo)
+		if err := writeTasks(tasks); err != nil {
+			return nil, nil, err
+		}
+		changes := diffTasks(loadedTasks, tasks)
+		if loadedTasks, err = cloneTasks(tasks); err != nil {
+			return nil, nil, err
+		}
+		if err := appendJournal(journalEntry{Kind: kind, Op: entry.Op, Ref: entry.Seq, Changes: changes}); err != nil {
+			return nil, nil, err
+		}
+		ops = append(ops, entry.Op)
+	}
+	return tasks, ops, nil
+}
+
+func describeChanges(changes []taskChange) string {
+	var parts []string
+	for _, change := range changes {
+		switch {
+		case change.Before == nil:
+			parts = append(parts, fmt.Sprintf("added %d", change.ID))
+		case change.After == nil:
+			parts = append(parts, fmt.Sprintf("removed %d", change.ID))
+		default:
+			parts = append(parts, fmt.Sprintf("changed %d", change.ID))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printLog shows the last n journal entries, newest first
+func printLog(n int) error {
+	entries, err := readJournal()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No changes recorded")
+		return nil
+	}
+	for i := len(entries) - 1; i >= 0 && i >= len(entries)-n; i-- {
+		entry := entries[i]
+		op := entry.Op
+		if entry.Kind != journalChange {
+			op = fmt.Sprintf("%s #%d (%s)", entry.Kind, entry.Ref, entry.Op)
+		}
+		fmt.Printf("%4d  %s  %-30s %s\n", entry.Seq, entry.Time.Format("2006-01-02 15:04:05"), op, describeChanges(entry.Changes))
+	}
+	return nil
+}
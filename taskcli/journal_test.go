@@ -0,0 +1,209 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTempTasksFile points tasksFile, and so the journal, at a new file for
+// the length of the test, starting IDs from 1
+func useTempTasksFile(t *testing.T) {
+	t.Helper()
+	oldFile, oldLoaded, oldOperation, oldHighest := tasksFile, loadedTasks, operation, highestID
+	tasksFile = filepath.Join(t.TempDir(), "tasks.json")
+	loadedTasks, highestID, archivedIDs = []Task{}, 0, nil
+	t.Cleanup(func() {
+		tasksFile, loadedTasks, operation, highestID = oldFile, oldLoaded, oldOperation, oldHighest
+	})
+}
+
+func mustCloneTasks(t *testing.T, tasks []Task) []Task {
+	t.Helper()
+	clone, err := cloneTasks(tasks)
+	if err != nil {
+		t.Fatalf("cloneTasks() error = %v", err)
+	}
+	return clone
+}
+
+func TestJournalStacks(t *testing.T) {
+	change := func(seq int) journalEntry { return journalEntry{Seq: seq, Kind: journalChange} }
+	undo := func(seq int) journalEntry { return journalEntry{Seq: seq, Kind: journalUndo} }
+	redo := func(seq int) journalEntry { return journalEntry{Seq: seq, Kind: journalRedo} }
+
+	tests := []struct {
+		name     string
+		entries  []journalEntry
+		wantUndo []int
+		wantRedo []int
+	}{
+		{name: "empty"},
+		{name: "changes", entries: []journalEntry{change(1), change(2)}, wantUndo: []int{1, 2}},
+		{name: "undo", entries: []journalEntry{change(1), change(2), undo(3)}, wantUndo: []int{1}, wantRedo: []int{2}},
+		{name: "undo twice", entries: []journalEntry{change(1), change(2), undo(3), undo(4)}, wantRedo: []int{2, 1}},
+		{name: "redo", entries: []journalEntry{change(1), change(2), undo(3), undo(4), redo(5)}, wantUndo: []int{1}, wantRedo: []int{2}},
+		{name: "new change clears redo", entries: []journalEntry{change(1), change(2), undo(3), change(4)}, wantUndo: []int{1, 4}},
+		{name: "undo past the start", entries: []journalEntry{change(1), undo(2), undo(3)}, wantRedo: []int{1}},
+		{name: "redo with nothing undone", entries: []journalEntry{change(1), redo(2)}, wantUndo: []int{1}},
+	}
+
+	seqs := func(entries []journalEntry) []int {
+		var seqs []int
+		for _, entry := range entries {
+			seqs = append(seqs, entry.Seq)
+		}
+		return seqs
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			undo, redo := journalStacks(tt.entries)
+			if got := seqs(undo); !reflect.DeepEqual(got, tt.wantUndo) {
+				t.Errorf("undo stack = %v, want %v", got, tt.wantUndo)
+			}
+			if got := seqs(redo); !reflect.DeepEqual(got, tt.wantRedo) {
+				t.Errorf("redo stack = %v, want %v", got, tt.wantRedo)
+			}
+		})
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	useTempTasksFile(t)
+
+	descriptions := func(tasks []Task) []string {
+		var names []string
+		for _, task := range tasks {
+			names = append(names, task.Description)
+		}
+		return names
+	}
+	check := func(step string, tasks []Task, want ...string) {
+		t.Helper()
+		if got := descriptions(tasks); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: tasks = %v, want %v", step, got, want)
+		}
+		// the file holds the same as memory
+		saved, err := loadTasks()
+		if err != nil {
+			t.Fatalf("%s: loadTasks() error = %v", step, err)
+		}
+		if got := descriptions(saved); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: saved tasks = %v, want %v", step, got, want)
+		}
+	}
+	save := func(op string, tasks []Task) []Task {
+		t.Helper()
+		operation = op
+		if err := saveTasks(tasks); err != nil {
+			t.Fatalf("saveTasks() error = %v", err)
+		}
+		return tasks
+	}
+	undoRedoOK := func(kind string, tasks []Task, n int, wantOps ...string) []Task {
+		t.Helper()
+		tasks, ops, err := undoRedo(tasks, kind, n)
+		if err != nil {
+			t.Fatalf("%s %d: error = %v", kind, n, err)
+		}
+		if !reflect.DeepEqual(ops, wantOps) {
+			t.Fatalf("%s %d: operations = %v, want %v", kind, n, ops, wantOps)
+		}
+		return tasks
+	}
+
+	if _, _, err := undoRedo([]Task{}, journalUndo, 1); err == nil || !strings.Contains(err.Error(), "nothing to undo") {
+		t.Fatalf("undo on an empty journal: error = %v, want nothing to undo", err)
+	}
+
+	tasks := save("add a", addTask([]Task{}, "a"))
+	tasks = save("add b", addTask(tasks, "b"))
+	tasks[0].Description = "a edited"
+	tasks = save("edit 1", tasks)
+	check("after the changes", tasks, "a edited", "b")
+
+	tasks = undoRedoOK(journalUndo, tasks, 1, "edit 1")
+	check("undo", tasks, "a", "b")
+	tasks = undoRedoOK(journalUndo, tasks, 1, "add b")
+	check("second undo", tasks, "a")
+	tasks = undoRedoOK(journalRedo, tasks, 2, "add b", "edit 1")
+	check("redo 2", tasks, "a edited", "b")
+
+	if _, _, err := undoRedo(tasks, journalUndo, 4); err == nil || !strings.Contains(err.Error(), "only 3") {
+		t.Fatalf("undo 4 of 3: error = %v, want only 3", err)
+	}
+
+	// a new change after an undo clears what could be redone
+	tasks = undoRedoOK(journalUndo, tasks, 1, "edit 1")
+	tasks = save("add c", addTask(tasks, "c"))
+	check("change after undo", tasks, "a", "b", "c")
+	if _, _, err := undoRedo(tasks, journalRedo, 1); err == nil || !strings.Contains(err.Error(), "nothing to redo") {
+		t.Fatalf("redo after a new change: error = %v, want nothing to redo", err)
+	}
+	tasks = undoRedoOK(journalUndo, tasks, 3, "add c", "add b", "add a")
+	check("undo everything", tasks)
+
+	// undo and redo are journalled too, so the log keeps the full history
+	entries, err := readJournal()
+	if err != nil {
+		t.Fatalf("readJournal() error = %v", err)
+	}
+	var kinds []string
+	for i, entry := range entries {
+		if entry.Seq != i+1 {
+			t.Errorf("entry %d has seq %d", i+1, entry.Seq)
+		}
+		kinds = append(kinds, entry.Kind)
+	}
+	want := []string{"change", "change", "change", "undo", "undo", "redo", "redo", "undo", "change", "undo", "undo", "undo"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("journal = %v, want %v", kinds, want)
+	}
+}
+
+func TestCloneTasks(t *testing.T) {
+	tasks := []Task{{ID: 1, Description: "a", BlockedBy: []int{2}}}
+	clone := mustCloneTasks(t, tasks)
+	clone[0].BlockedBy[0] = 3
+	if tasks[0].BlockedBy[0] != 2 {
+		t.Errorf("editing the clone changed the original: %+v", tasks)
+	}
+
+	// a time JSON cannot hold is an error rather than a panic
+	far := time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := cloneTasks([]Task{{ID: 1, Due: &far}}); err == nil {
+		t.Errorf("cloneTasks() of a task due in year 10000 succeeded, want an error")
+	}
+}
+
+func TestAppendJournalSeq(t *testing.T) {
+	useTempTasksFile(t)
+	if seq, err := lastJournalSeq(); err != nil || seq != 0 {
+		t.Fatalf("lastJournalSeq() without a journal = %d, %v, want 0", seq, err)
+	}
+	// the second entry is longer than the first chunk read from the end
+	long := strings.Repeat("x", 10000)
+	for i, description := range []string{"a", long, "c"} {
+		entry := journalEntry{Kind: journalChange, Op: "add", Changes: []taskChange{{ID: i + 1, After: &Task{ID: i + 1, Description: description}}}}
+		if err := appendJournal(entry); err != nil {
+			t.Fatalf("appendJournal() error = %v", err)
+		}
+		if seq, err := lastJournalSeq(); err != nil || seq != i+1 {
+			t.Errorf("lastJournalSeq() after %d entries = %d, %v", i+1, seq, err)
+		}
+	}
+	entries, err := readJournal()
+	if err != nil {
+		t.Fatalf("readJournal() error = %v", err)
+	}
+	for i, entry := range entries {
+		if entry.Seq != i+1 {
+			t.Errorf("entry %d has seq %d", i+1, entry.Seq)
+		}
+	}
+	if len(entries) != 3 {
+		t.Errorf("journal has %d entries, want 3", len(entries))
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	colorRed   = "\033[31m"
+	colorReset = "\033[0m"
+)
+
+type listOptions struct {
+	sortBy   string     // id, due, priority or created
+	due      *time.Time // only tasks due on this day
+	overdue  bool       // only pending tasks past their due date
+	priority string     // only tasks with this priority letter
+	project  string     // only tasks in this +project
+	context  string     // only tasks with this @context
+	tag      string     // only tasks with this #tag
+	status   string     // only tasks with this status
+	groupBy  string     // project, context, tag or empty for no grouping
+	tree     bool       // show subtasks indented under their parent
+	filter   taskFilter // only tasks matching the filter expression
+}
+
+func parseListOptions(args []string) (listOptions, error) {
+	var opts listOptions
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	sortBy := listCmd.String("sort", "id", "Sort by id, due, priority or created")
+	due := listCmd.String("due", "", "Only show tasks due on this date (e.g. today)")
+	listCmd.BoolVar(&opts.overdue, "overdue", false, "Only show overdue tasks")
+	priority := listCmd.String("priority", "", "Only show tasks with this priority (A-D or high/medium/low)")
+	listCmd.StringVar(&opts.project, "project", "", "Only show tasks in this project")
+	listCmd.StringVar(&opts.context, "context", "", "Only show tasks with this context")
+	listCmd.StringVar(&opts.tag, "tag", "", "Only show tasks with this tag")
+	listCmd.StringVar(&opts.status, "status", "", "Only show tasks with this status")
+	listCmd.StringVar(&opts.groupBy, "group", "", "Group tasks by project, context or tag")
+	listCmd.BoolVar(&opts.tree, "tree", false, "Show subtasks indented under their parent")
+	listCmd.Parse(args)
+
+	// the expression may be one quoted argument or spread over several
+	if expr := strings.Join(listCmd.Args(), " "); strings.TrimSpace(expr) != "" {
+		filter, err := parseFilter(expr, config)
+		if err != nil {
+			return opts, fmt.Errorf("invalid filter: %w", err)
+		}
+		opts.filter = filter
+	}
+
+	// accept the tokens as typed in descriptions, e.g. --project +visualiser
+	opts.project = strings.TrimPrefix(opts.project, "+")
+	opts.context = strings.TrimPrefix(opts.context, "@")
+	opts.tag = strings.TrimPrefix(opts.tag, "#")
+	switch opts.groupBy {
+	case "", "project", "context", "tag":
+	default:
+		return opts, fmt.Errorf("invalid group %q: use project, context or tag", opts.groupBy)
+	}
+
+	switch *sortBy {
+	case "id", "due", "priority", "created":
+		opts.sortBy = *sortBy
+	default:
+		return opts, fmt.Errorf("invalid sort %q: use id, due, priority or created", *sortBy)
+	}
+	if *due != "" {
+		dueDate, err := parseDate(*due)
+		if err != nil {
+			return opts, err
+		}
+		opts.due = &dueDate
+	}
+	if *priority != "" {
+		p, err := parsePriority(*priority)
+		if err != nil {
+			return opts, err
+		}
+		opts.priority = p
+	}
+	return opts, nil
+}
+
+func (opts listOptions) matches(task Task) bool {
+	if opts.due != nil && (task.Due == nil || !sameDay(*task.Due, *opts.due)) {
+		return false
+	}
+	if opts.overdue && !task.isOverdue() {
+		return false
+	}
+	if opts.priority != "" && task.Priority != opts.priority {
+		return false
+	}
+	if opts.project != "" && !containsFold(task.Projects, opts.project) {
+		return false
+	}
+	if opts.context != "" && !containsFold(task.Contexts, opts.context) {
+		return false
+	}
+	if opts.tag != "" && !containsFold(task.Tags, opts.tag) {
+		return false
+	}
+	if opts.status != "" && task.Status != opts.status {
+		return false
+	}
+	if opts.filter != nil && !opts.filter(task) {
+		return false
+	}
+	return true
+}
+
+// timeLess orders nil times after all others
+func timeLess(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a != nil && b == nil
+	}
+	return a.Before(*b)
+}
+
+func sortTasks(tasks []Task, by string) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		switch by {
+		case "due":
+			if !timeEqual(a.Due, b.Due) {
+				return timeLess(a.Due, b.Due)
+			}
+		case "priority":
+			if priorityRank(a.Priority) != priorityRank(b.Priority) {
+				return priorityRank(a.Priority) < priorityRank(b.Priority)
+			}
+		case "created":
+			if !timeEqual(a.CreatedAt, b.CreatedAt) {
+				return timeLess(a.CreatedAt, b.CreatedAt)
+			}
+		}
+		return a.ID < b.ID
+	})
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// useColor reports whether stdout is a terminal that should get colors
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func formatTask(task Task) string {
+	var b strings.Builder
+	check := " "
+	if task.isCompleted() {
+		check = "x"
+	} else if task.Status != config.initialStatus() {
+		check = "~"
+	}
+	fmt.Fprintf(&b, "%3d. [%s] ", task.ID, check)
+	if task.Priority != "" {
+		fmt.Fprintf(&b, "(%s) ", task.Priority)
+	}
+	b.WriteString(task.Description)
+	if !task.isCompleted() && task.Status != config.initialStatus() {
+		fmt.Fprintf(&b, "  {%s}", task.Status)
+	}
+	if task.Due != nil {
+		fmt.Fprintf(&b, "  due:%s", task.Due.Format(dateLayout))
+		if task.isOverdue() {
+			b.WriteString(" (overdue)")
+		}
+	}
+	if task.Recur != "" {
+		fmt.Fprintf(&b, "  (%s)", task.Recur)
+	}
+	if len(task.TimeEntries) > 0 {
+		fmt.Fprintf(&b, "  [%s", formatDuration(task.trackedTime()))
+		if task.isRunning() {
+			b.WriteString(", running")
+		}
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+// taskPrinter prints task lines, using the whole task list to show which
+// open tasks block each one
+type taskPrinter struct {
+	all   []Task
+	color bool
+}
+
+func (p taskPrinter) print(task Task, indent string) {
+	line := formatTask(task)
+	if !task.isCompleted() {
+		if _, blockers := openDependencies(p.all, task.ID); len(blockers) > 0 {
+			line += "  (blocked by " + formatIDs(blockers) + ")"
+		}
+	}
+	if p.color && task.isOverdue() {
+		line = colorRed + line + colorReset
+	}
+	fmt.Println("  " + indent + line)
+}
+
+// printTree prints tasks under their parents. Tasks whose parent is not
+// among them start a new tree.
+func (p taskPrinter) printTree(tasks []Task) {
+	shown := map[int]bool{}
+	for _, task := range tasks {
+		shown[task.ID] = true
+	}
+	var walk func(parent int, indent string)
+	walk = func(parent int, indent string) {
+		for _, task := range tasks {
+			isRoot := parent == 0 && !shown[task.Parent]
+			if task.Parent == parent && parent != 0 || isRoot {
+				p.print(task, indent)
+				walk(task.ID, indent+"    ")
+			}
+		}
+	}
+	walk(0, "")
+}
+
+func listTasks(tasks []Task, opts listOptions) {
+	var shown []Task
+	for _, task := range tasks {
+		if opts.matches(task) {
+			shown = append(shown, task)
+		}
+	}
+	if len(shown) == 0 {
+		fmt.Println("No tasks")
+		return
+	}
+	sortTasks(shown, opts.sortBy)
+
+	printer := taskPrinter{all: tasks, color: useColor()}
+	if opts.tree {
+		printer.printTree(shown)
+		return
+	}
+	if opts.groupBy == "" {
+		printer.printSection("Pending:", shown, func(t Task) bool { return !t.isCompleted() })
+		printer.printSection("Completed:", shown, func(t Task) bool { return t.isCompleted() })
+		return
+	}
+
+	prefixes := map[string]string{"project": "+", "context": "@", "tag": "#"}
+	names, groups := groupTasks(shown, opts.groupBy)
+	for _, name := range names {
+		heading := prefixes[opts.groupBy] + name + ":"
+		if name == "" {
+			heading = "(no " + opts.groupBy + "):"
+		}
+		fmt.Println(heading)
+		for _, task := range groups[name] {
+			printer.print(task, "")
+		}
+	}
+}
+
+// printSection prints the tasks selected by include under a heading,
+// skipping the heading when there are none
+func (p taskPrinter) printSection(heading string, tasks []Task, include func(Task) bool) {
+	var selected []Task
+	for _, task := range tasks {
+		if include(task) {
+			selected = append(selected, task)
+		}
+	}
+	if len(selected) == 0 {
+		return
+	}
+	fmt.Println(heading)
+	for _, task := range selected {
+		p.print(task, "")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSortTasks(t *testing.T) {
+	day := func(n int) *time.Time {
+		d := today().AddDate(0, 0, n)
+		return &d
+	}
+	tasks := []Task{
+		{ID: 1, Description: "no due, no priority", CreatedAt: day(-1)},
+		{ID: 2, Description: "due later", Priority: "C", Due: day(5), CreatedAt: day(-3)},
+		{ID: 3, Description: "due soon", Priority: "A", Due: day(1)},
+		{ID: 4, Description: "due soon too", Priority: "C", Due: day(1), CreatedAt: day(-2)},
+	}
+
+	tests := []struct {
+		by   string
+		want []int
+	}{
+		{by: "id", want: []int{1, 2, 3, 4}},
+		// ties keep ID order, and tasks without the field go last
+		{by: "due", want: []int{3, 4, 2, 1}},
+		{by: "priority", want: []int{3, 2, 4, 1}},
+		{by: "created", want: []int{2, 4, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			sorted := append([]Task(nil), tasks...)
+			// start from the reverse so the order cannot come from the input
+			for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+				sorted[i], sorted[j] = sorted[j], sorted[i]
+			}
+			sortTasks(sorted, tt.by)
+			var got []int
+			for _, task := range sorted {
+				got = append(got, task.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortTasks(%q) = %v, want %v", tt.by, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListOptions(t *testing.T) {
+	opts, err := parseListOptions([]string{"--sort", "due", "--due", "tomorrow", "--priority", "high", "--overdue"})
+	if err != nil {
+		t.Fatalf("parseListOptions() error = %v", err)
+	}
+	if opts.sortBy != "due" || opts.due == nil || !opts.due.Equal(today().AddDate(0, 0, 1)) || opts.priority != "A" || !opts.overdue {
+		t.Errorf("parseListOptions() = %+v", opts)
+	}
+
+	if opts, err := parseListOptions(nil); err != nil || opts.sortBy != "id" {
+		t.Errorf("parseListOptions(nil) = %+v, %v, want sorting by id", opts, err)
+	}
+
+	for _, args := range [][]string{{"--sort", "name"}, {"--due", "someday"}, {"--priority", "Z"}} {
+		if _, err := parseListOptions(args); err == nil {
+			t.Errorf("parseListOptions(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestListOptionsMatches(t *testing.T) {
+	yesterday, tomorrow := today().AddDate(0, 0, -1), today().AddDate(0, 0, 1)
+	tasks := []Task{
+		{ID: 1, Description: "overdue", Priority: "A", Due: &yesterday},
+		{ID: 2, Description: "done late", Status: "done", Due: &yesterday},
+		{ID: 3, Description: "due tomorrow", Priority: "B", Due: &tomorrow},
+		{ID: 4, Description: "no due date", Priority: "A"},
+	}
+
+	tests := []struct {
+		name string
+		opts listOptions
+		want []int
+	}{
+		{name: "everything", opts: listOptions{}, want: []int{1, 2, 3, 4}},
+		{name: "due on a day", opts: listOptions{due: &tomorrow}, want: []int{3}},
+		{name: "overdue leaves out completed tasks", opts: listOptions{overdue: true}, want: []int{1}},
+		{name: "priority", opts: listOptions{priority: "A"}, want: []int{1, 4}},
+		{name: "combined", opts: listOptions{priority: "A", overdue: true}, want: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, task := range tasks {
+				if tt.opts.matches(task) {
+					got = append(got, task.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches() selects %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	due := time.Date(2099, 1, 31, 0, 0, 0, 0, time.Local)
+	tasks := []Task{
+		{ID: 1, Description: "Write report", Status: "done"},
+		{ID: 2, Description: "Send report", Status: "todo", Priority: "B", Due: &due},
+		{ID: 4, Description: "File expenses", Status: "todo", Priority: "A"},
+	}
+	got := captureStdout(t, func() { listTasks(tasks, listOptions{sortBy: "priority"}) })
+	want := "Pending:\n" +
+		"    4. [ ] (A) File expenses\n" +
+		"    2. [ ] (B) Send report  due:2099-01-31\n" +
+		"Completed:\n" +
+		"    1. [x] Write report\n"
+	if got != want {
+		t.Errorf("listTasks() printed\n%s\nwant\n%s", got, want)
+	}
+
+	if got := captureStdout(t, func() { listTasks(tasks, listOptions{sortBy: "id", priority: "D"}) }); got != "No tasks\n" {
+		t.Errorf("listTasks() with nothing selected printed %q, want %q", got, "No tasks\n")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// acquireLock creates path exclusively, waiting up to lockWait for another
+// process to remove it. A lock left behind by a crashed process is taken
+// over once it is a minute old.
+func acquireLock(path string) (func(), error) {
+	deadline := time.Now().Add(lockWait)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > time.Minute {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errLockTimeout
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
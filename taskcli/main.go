@@ -1,69 +1,446 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
-const tasksFile = "tasks.json" // File for persisting tasks 
+
+// File for persisting tasks, chosen by resolveTasksFile; a .txt file is
+// stored in todo.txt format.
+var tasksFile = "tasks.json"
 
 type Task struct {
-	Description string `json:"description"`
-	Completed   bool   `json:"completed"`
+	ID          int          `json:"id"`
+	Description string       `json:"description"`
+	Status      string       `json:"status"`              // one of config.Statuses
+	Completed   *bool        `json:"completed,omitempty"` // replaced by Status; only read from old journal entries
+	Priority    string       `json:"priority,omitempty"`  // A (highest) to D
+	Due         *time.Time   `json:"due,omitempty"`
+	CreatedAt   *time.Time   `json:"created_at,omitempty"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+	ModifiedAt  *time.Time   `json:"modified_at,omitempty"`  // last change, for sync to pick the newer version
+	Projects    []string     `json:"projects,omitempty"`     // +project words in the description
+	Contexts    []string     `json:"contexts,omitempty"`     // @context words
+	Tags        []string     `json:"tags,omitempty"`         // #tag words
+	Parent      int          `json:"parent,omitempty"`       // ID of the task this is a subtask of
+	BlockedBy   []int        `json:"blocked_by,omitempty"`   // IDs of tasks that must be done first
+	Recur       string       `json:"recur,omitempty"`        // recurrence rule, e.g. "every monday"
+	RecurDay    int          `json:"recur_day,omitempty"`    // day of the month the rule falls on, when a short month moved Due earlier
+	TimeEntries []timeEntry  `json:"time_entries,omitempty"` // time tracked with start/stop
+	UID         string       `json:"uid,omitempty"`          // iCalendar UID of an imported task
+	Source      *taskSource  `json:"source,omitempty"`       // file and line the task was imported from
+	Annotations []annotation `json:"annotations,omitempty"`  // timestamped remarks added with note
+	Notes       string       `json:"notes,omitempty"`        // long-form notes, edited with notes
+	Links       []string     `json:"links,omitempty"`        // URLs in the description, annotations and notes
+}
+
+// isOverdue reports whether a pending task's due date has passed
+func (t Task) isOverdue() bool {
+	return !t.isCompleted() && t.Due != nil && t.Due.Before(today())
+}
+
+// isCompleted reports whether the task is in the final status
+func (t Task) isCompleted() bool {
+	return t.Status == config.doneStatus()
+}
+
+// migrateStatus turns the completed flag of older journal entries into a
+// status (files are migrated when decoded, see schema.go) and gives tasks
+// without a status the initial one
+func migrateStatus(task *Task) {
+	if task.Completed != nil {
+		if *task.Completed {
+			task.Status = config.doneStatus()
+		}
+		task.Completed = nil
+	}
+	if task.Status == "" {
+		task.Status = config.initialStatus()
+	}
 }
 
-// loadTasks loads tasks from the JSON file
+// loadTasks loads tasks from the tasks file
 func loadTasks() ([]Task, error) {
 	// first check if the file exists
 	if _, err := os.Stat(tasksFile); os.IsNotExist(err) {
+		loadedTasks = []Task{}
 		return []Task{}, nil
 	}
-	// the file exists, read the file
-	data, err := ioutil.ReadFile(tasksFile)
-	if err != nil {
+	// keep a copy of a file in an older schema before it is migrated
+	if err := backupBeforeMigrating(tasksFile); err != nil {
 		return nil, err
 	}
-	// unmarshal the data into a slice of Task
-	var tasks []Task
-	err = json.Unmarshal(data, &tasks)
+	// the file exists, decode it in the format its extension names
+	tasks, next, err := readTasksWithNextID()
 	if err != nil {
 		return nil, err
 	}
+	// new tasks are numbered after every ID the file has handed out
+	highestID = max(highestID, next-1)
+	// tasks saved before IDs existed get one now, after the highest known ID
+	assignIDs(tasks)
+	for i := range tasks {
+		migrateStatus(&tasks[i])
+	}
+	// remember what was loaded so saveTasks can journal the changes
+	if loadedTasks, err = cloneTasks(tasks); err != nil {
+		return nil, err
+	}
 	// return the tasks
 	return tasks, nil
-	return nil, nil
 }
 
+// readTasksFile decodes a task file in the given format
+func readTasksFile(path string, format string) ([]Task, error) {
+	codec, err := codecFor(format)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return codec.decode(file)
+}
+
+// highestID is the highest task ID handed out so far. The tasks file keeps
+// it, so the ID of a removed or archived task is not given to a new
+// one, which would mix the two up in the journal and on import.
+var highestID int
+
+// nextID returns the ID for a new task. IDs are stored with the task, so a
+// task keeps its number when others are completed or removed.
+func nextID(tasks []Task) int {
+	maxID := highestID
+	for _, task := range tasks {
+		if task.ID > maxID {
+			maxID = task.ID
+		}
+	}
+	highestID = maxID + 1
+	return highestID
+}
+
+// nextIDToSave is the next_id stored with the tasks: one more than the
+// highest ID handed out or in the list
+func nextIDToSave(tasks []Task) int {
+	next := highestID + 1
+	for _, task := range tasks {
+		next = max(next, task.ID+1)
+	}
+	return next
+}
+
+// nextIDFile keeps next_id for a tasks file in a format with no room for
+// it. Any extra line in a todo.txt file would be a task to other tools.
+func nextIDFile() string {
+	return tasksFile + ".nextid"
+}
+
+// readTasksWithNextID decodes the tasks file along with its next_id, which
+// a JSON file holds itself and other formats keep in nextIDFile. The
+// next_id is 0 if it was never saved.
+func readTasksWithNextID() ([]Task, int, error) {
+	format := formatForPath(tasksFile)
+	if format == "json" {
+		data, err := os.ReadFile(tasksFile)
+		if err != nil {
+			return nil, 0, err
+		}
+		return decodeTasksDocument(data)
+	}
+	tasks, err := readTasksFile(tasksFile, format)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := os.ReadFile(nextIDFile())
+	if os.IsNotExist(err) {
+		return tasks, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	next, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid next ID in %s: %w", nextIDFile(), err)
+	}
+	return tasks, next, nil
+}
+
+func assignIDs(tasks []Task) {
+	for i := range tasks {
+		if tasks[i].ID == 0 {
+			tasks[i].ID = nextID(tasks)
+		}
+	}
+}
+
+// findTask returns the index of the task with the given ID, or -1
+func findTask(tasks []Task, id int) int {
+	for i, task := range tasks {
+		if task.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveTasks writes the tasks and journals what changed since they were
+// loaded, so the operation can be undone
 func saveTasks(tasks []Task) error {
-	// write tasks to file
-	data, err := json.MarshalIndent(tasks, "", "  ")
-	if err != nil {	
+	stampModified(loadedTasks, tasks)
+	if err := writeTasks(tasks); err != nil {
+		return err
+	}
+	changes := diffTasks(loadedTasks, tasks)
+	archived := archivedIDs
+	archivedIDs = nil
+	snapshot, err := cloneTasks(tasks)
+	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(tasksFile, data, 0644)	
+	loadedTasks = snapshot
+	if len(changes) == 0 {
+		return nil
+	}
+	return appendJournal(journalEntry{Kind: journalChange, Op: operation, Changes: changes, Archived: archived})
 }
 
-func listTasks(tasks []Task) {}
+func writeTasks(tasks []Task) error {
+	// tick the boxes of checklist items before their state is saved
+	writeBackMarkdown(loadedTasks, tasks)
+	// encode tasks in the file's format, then write them
+	codec, err := codecFor(formatForPath(tasksFile))
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := codec.encode(&buf, tasks); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(tasksFile, buf.Bytes()); err != nil {
+		return err
+	}
+	if formatForPath(tasksFile) == "json" {
+		return nil
+	}
+	return writeFileAtomic(nextIDFile(), []byte(strconv.Itoa(nextIDToSave(tasks))+"\n"))
+}
 
 func addTask(tasks []Task, description string) []Task {
-	tasks = append(tasks, Task{Description: description, Completed: false})
+	now := time.Now().Truncate(time.Second)
+	task := Task{ID: nextID(tasks), Description: description, Status: config.initialStatus(), CreatedAt: &now}
+	parseDescription(&task)
+	tasks = append(tasks, task)
 	return tasks
 }
 
-func completeTask(tasks []Task, index int) ([]Task, error) {
-	return nil, nil
+// completeTask marks the task with the given ID as completed. Completing a
+// recurring task adds its next occurrence to the end of the list. On an
+// error the tasks are left as they were.
+func completeTask(tasks []Task, id int) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	if tasks[i].isCompleted() {
+		return nil, fmt.Errorf("task %d is already completed", id)
+	}
+	// the next occurrence comes first, as it is the part that can fail
+	var next []Task
+	if tasks[i].Recur != "" {
+		occurrence, err := nextOccurrence(tasks, tasks[i])
+		if err != nil {
+			return nil, err
+		}
+		next = append(next, occurrence)
+	}
+	stopTimer(tasks, i)
+	now := time.Now().Truncate(time.Second)
+	tasks[i].Status = config.doneStatus()
+	tasks[i].CompletedAt = &now
+	return append(tasks, next...), nil
+}
+
+// parseID parses a task ID given on the command line
+func parseID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid task ID %q: must be a positive number", arg)
+	}
+	return id, nil
+}
+
+// initLocalTasks creates an empty per-directory task list
+func initLocalTasks() error {
+	path := localTaskFiles[0]
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	// an empty list in the current layout, so the first run has nothing
+	// to migrate
+	data, err := encodeTasksDocument(nil)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	fmt.Println("Created", path, "- taskcli will use it in this directory and below")
+	return nil
+}
+
+func printUsage() {
+	fmt.Println("Usage: taskcli [--file path] <action> [options]")
+	fmt.Println("  init                      start a task list in this directory")
+	fmt.Println("  add [--priority A] [--due date] [--parent id] [--recur rule] description")
+	fmt.Println("  list [--sort id|due|priority|created] [--due date] [--overdue] [--priority A]")
+	fmt.Println("       [--project name] [--context name] [--tag name] [--status name] [--group project|context|tag] [--tree]")
+	fmt.Println("       ['filter expression' | saved-filter]")
+	fmt.Println("  done [--force] ids|filters    (ids: 3, 3-7 or 3,5,9-11)")
+	fmt.Println("  undone ids|filters | rm ids|filters")
+	fmt.Println("       filters: [--completed|--pending] [--project name] [--before 30d]")
+	fmt.Println("  edit id description")
+	fmt.Println("  prio ids A-D|high|medium|low|none")
+	fmt.Println("  note id text              add a timestamped annotation")
+	fmt.Println("  notes id                  edit the task's notes in $EDITOR")
+	fmt.Println("  show id                   everything about a task, with its history")
+	fmt.Println("  archive [ids|filters]     move completed tasks to the archive file")
+	fmt.Println("  blocks id blocked-id      (blocked-by blocked-id id)")
+	fmt.Println("  unblock blocked-id id")
+	fmt.Println("  import [--format json|todotxt|ics] file")
+	fmt.Println("  export [--format json|todotxt|ics] [--out file]")
+	fmt.Println("  sync-md file              sync tasks with a markdown checklist")
+	fmt.Println("  scan [--project name] dir turn TODO/FIXME/XXX comments into tasks")
+	fmt.Println("  board                     show tasks in a column per status")
+	fmt.Println("  ui                        full-screen interface")
+	fmt.Println("  serve [--addr host:port]  JSON API and web dashboard")
+	fmt.Println("  sync [--server url] [--token t]  sync tasks with a taskcli server")
+	fmt.Println("  server [--addr host:port] [--data file] [--token t]  run a sync server")
+	fmt.Println("  agenda [--days n]         overdue tasks and those due today")
+	fmt.Println("  watch [--lead 1h] [--interval 1m] [--stdout]  remind of tasks before they are due")
+	fmt.Println("  mv [--force] ids status   move tasks to another status, e.g. mv 3 review")
+	fmt.Println("  start id | stop            track time spent on a task")
+	fmt.Println("  report [--week] [--csv file]")
+	fmt.Println("  stats [--weeks n] [--project name] [--json] ['filter expression']")
+	fmt.Println("  undo [n] | redo [n]")
+	fmt.Println("  log [-n count]")
 }
 
 func main() {
-	if (len(os.Args) < 2) {
-		fmt.Println("Usage: taskcli [add|list|done] [task description|task number]")
+	globalCmd := flag.NewFlagSet("taskcli", flag.ExitOnError)
+	file := globalCmd.String("file", "", "Tasks file (default: TASKCLI_FILE, a .tasks.json found upwards, or the XDG data dir)")
+	globalCmd.Usage = printUsage
+	globalCmd.Parse(os.Args[1:])
+	args := globalCmd.Args()
+	if len(args) < 1 {
+		printUsage()
 		return
 	}
 
 	var action string
 	var taskDescription string
-	action = os.Args[1]
+	action = args[0]
+	operation = strings.Join(args, " ")
+
+	if action == "init" {
+		if err := initLocalTasks(); err != nil {
+			fmt.Println("Error:", err)
+		}
+		return
+	}
+
+	if err := loadConfig(); err != nil {
+		fmt.Println("Error loading config:", err)
+		return
+	}
+
+	var err error
+	if tasksFile, err = resolveTasksFile(*file); err != nil {
+		fmt.Println("Error finding tasks file:", err)
+		return
+	}
+	// long-running actions take the lock for each change they make
+	switch action {
+	case "ui":
+		if err := runUI(); err != nil {
+			fmt.Println("Error:", err)
+		}
+		return
+	case "serve":
+		serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := serveCmd.String("addr", "127.0.0.1:8080", "Address to listen on")
+		serveCmd.Parse(args[1:])
+		if err := runServer(*addr); err != nil {
+			fmt.Println("Error:", err)
+		}
+		return
+	case "server":
+		serverCmd := flag.NewFlagSet("server", flag.ExitOnError)
+		addr := serverCmd.String("addr", "127.0.0.1:8090", "Address to listen on")
+		data := serverCmd.String("data", "", "File the server keeps tasks in (default: server.json in the XDG data dir)")
+		token := serverCmd.String("token", os.Getenv("TASKCLI_SYNC_TOKEN"), "Token clients must send (default: TASKCLI_SYNC_TOKEN)")
+		serverCmd.Parse(args[1:])
+		path := *data
+		if path == "" {
+			if path, err = syncServerFile(); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+		}
+		if err := runSyncServer(*addr, path, *token); err != nil {
+			fmt.Println("Error:", err)
+		}
+		return
+	case "notes":
+		if len(args) != 2 {
+			fmt.Println("Usage: taskcli notes id")
+			return
+		}
+		id, err := parseID(args[1])
+		if err == nil {
+			err = editNotes(id)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+		}
+		return
+	case "watch":
+		watchCmd := flag.NewFlagSet("watch", flag.ExitOnError)
+		lead := watchCmd.String("lead", config.ReminderLead, "Remind this long before a task is due, e.g. 30m, 2h or 1d")
+		interval := watchCmd.Duration("interval", time.Minute, "How often to check the tasks")
+		stdout := watchCmd.Bool("stdout", false, "Print reminders instead of using notify-send")
+		watchCmd.Parse(args[1:])
+		leadTime, err := parseLead(*lead)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if *interval <= 0 {
+			fmt.Println("Error: --interval must be positive, e.g. 30s or 5m")
+			return
+		}
+		fmt.Printf("Watching %s, reminding %s before tasks are due at %s\n", tasksFile, formatDuration(leadTime), config.DueTime)
+		watchTasks(leadTime, *interval, newNotifier(*stdout))
+		return
+	}
+
+	// hold the lock from loading until saving, so concurrent runs queue up
+	// instead of overwriting each other's changes
+	unlock, err := lockTasks()
+	if err != nil {
+		fmt.Println("Error locking tasks:", err)
+		return
+	}
+	defer unlock()
 
 	// load tasks from file, as this will be used for all actions
 	tasks, err := loadTasks()
@@ -73,12 +450,51 @@ func main() {
 	}
 	switch action {
 	case "add":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: taskcli add [task description]")
+		addCmd := flag.NewFlagSet("add", flag.ExitOnError)
+		priority := addCmd.String("priority", "", "Priority: A-D or high/medium/low")
+		due := addCmd.String("due", "", "Due date: YYYY-MM-DD, today, tomorrow, a weekday or Nd")
+		parent := addCmd.Int("parent", 0, "Make the new task a subtask of this task ID")
+		recur := addCmd.String("recur", "", `Repeat the task, e.g. "every day", "every monday", "every 2 weeks", "monthly on the 1st"`)
+		addCmd.Parse(args[1:])
+		if addCmd.NArg() < 1 {
+			fmt.Println("Usage: taskcli add [--priority A] [--due date] [--parent id] [--recur rule] [task description]")
+			return
+		}
+		if *parent != 0 && findTask(tasks, *parent) < 0 {
+			fmt.Printf("Error: no task with ID %d\n", *parent)
 			return
 		}
-		taskDescription = os.Args[2]
+		taskDescription = strings.Join(addCmd.Args(), " ")
 		tasks = addTask(tasks, taskDescription)
+		task := &tasks[len(tasks)-1]
+		if *priority != "" {
+			if task.Priority, err = parsePriority(*priority); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+		}
+		if *due != "" {
+			dueDate, err := parseDate(*due)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			task.Due = &dueDate
+		}
+		task.Parent = *parent
+		if *recur != "" {
+			rule, err := parseRecurrence(*recur)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			task.Recur = *recur
+			// a recurring task is always due somewhere on its schedule
+			if task.Due == nil {
+				first := rule.first(today())
+				task.Due = &first
+			}
+		}
 		// Save the updated tasks
 		if err := saveTasks(tasks); err != nil {
 			fmt.Println("Error saving tasks:", err)
@@ -86,9 +502,540 @@ func main() {
 		}
 		fmt.Println("Task added:", taskDescription)
 	case "list":
+		opts, err := parseListOptions(args[1:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		listTasks(tasks, opts)
 	case "done":
+		doneCmd := flag.NewFlagSet("done", flag.ExitOnError)
+		force := doneCmd.Bool("force", false, "Complete tasks even if subtasks or blockers are open")
+		selection := addSelectionFlags(doneCmd)
+		doneCmd.Parse(args[1:])
+		ids, err := selectTasks(tasks, doneCmd.Args(), selection)
+		if err != nil {
+			fmt.Println("Error:", err)
+			fmt.Println("Usage: taskcli done [--force] [ids|ranges] [--pending] [--project name] [--before age]")
+			return
+		}
+		completed := 0
+		for _, id := range ids {
+			children, blockers := openDependencies(tasks, id)
+			if len(children) > 0 || len(blockers) > 0 {
+				if len(children) > 0 {
+					fmt.Printf("Task %d has open subtasks: %s\n", id, formatIDs(children))
+				}
+				if len(blockers) > 0 {
+					fmt.Printf("Task %d is blocked by open tasks: %s\n", id, formatIDs(blockers))
+				}
+				if !*force {
+					fmt.Println("Not completed; use done --force to complete it anyway")
+					continue
+				}
+				fmt.Println("Warning: completing anyway")
+			}
+			before := len(tasks)
+			updated, err := completeTask(tasks, id)
+			if err != nil {
+				fmt.Println("Error:", err)
+				continue
+			}
+			tasks = updated
+			completed++
+			fmt.Println("Task completed:", tasks[findTask(tasks, id)].Description)
+			if len(tasks) > before {
+				next := tasks[len(tasks)-1]
+				fmt.Printf("Next occurrence: %d, due %s\n", next.ID, next.Due.Format(dateLayout))
+			}
+		}
+		if completed == 0 {
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+	case "undone", "rm", "archive":
+		bulkCmd := flag.NewFlagSet(action, flag.ExitOnError)
+		selection := addSelectionFlags(bulkCmd)
+		bulkCmd.Parse(args[1:])
+		specs := bulkCmd.Args()
+		// only completed tasks are archived, all of them unless narrowed down
+		if action == "archive" {
+			selection.completed = true
+		}
+		ids, err := selectTasks(tasks, specs, selection)
+		if err != nil {
+			fmt.Println("Error:", err)
+			fmt.Printf("Usage: taskcli %s [ids|ranges] [--completed|--pending] [--project name] [--before age]\n", action)
+			return
+		}
+		if len(ids) == 0 {
+			fmt.Println("No matching tasks")
+			return
+		}
+		switch action {
+		case "undone":
+			for _, id := range ids {
+				updated, err := reopenTask(tasks, id)
+				if err != nil {
+					fmt.Println("Error:", err)
+					continue
+				}
+				tasks = updated
+				fmt.Println("Task reopened:", tasks[findTask(tasks, id)].Description)
+			}
+		case "rm":
+			tasks = removeTasks(tasks, ids)
+			fmt.Printf("Removed %d task(s): %s\n", len(ids), formatIDs(ids))
+		case "archive":
+			if tasks, err = archiveTasks(tasks, ids); err != nil {
+				fmt.Println("Error archiving tasks:", err)
+				return
+			}
+			fmt.Printf("Archived %d task(s) to %s\n", len(ids), archiveFile())
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+	case "edit":
+		if len(args) < 3 {
+			fmt.Println("Usage: taskcli edit id \"new description\"")
+			return
+		}
+		id, err := parseID(args[1])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if tasks, err = editTask(tasks, id, strings.Join(args[2:], " ")); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		fmt.Println("Task updated:", tasks[findTask(tasks, id)].Description)
+	case "prio":
+		if len(args) < 3 {
+			fmt.Println("Usage: taskcli prio ids|ranges A-D|high|medium|low|none")
+			return
+		}
+		priority := ""
+		if args[2] != "none" && args[2] != "-" {
+			if priority, err = parsePriority(args[2]); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+		}
+		ids, err := selectTasks(tasks, args[1:2], &selectionOptions{})
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		for _, id := range ids {
+			if tasks, err = setPriority(tasks, id, priority); err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		fmt.Printf("Priority of %s set to %s\n", formatIDs(ids), args[2])
+	case "blocks", "blocked-by", "unblock":
+		if len(args) < 3 {
+			fmt.Println("Usage: taskcli blocks id blocked-id | blocked-by blocked-id id | unblock blocked-id id")
+			return
+		}
+		first, err := parseID(args[1])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		second, err := parseID(args[2])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		// normalise to "blocked is blocked by blocker"
+		blocked, blocker := first, second
+		if action == "blocks" {
+			blocked, blocker = second, first
+		}
+		if action == "unblock" {
+			tasks, err = removeBlocker(tasks, blocked, blocker)
+		} else {
+			tasks, err = addBlocker(tasks, blocked, blocker)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		if action == "unblock" {
+			fmt.Printf("Task %d is no longer blocked by %d\n", blocked, blocker)
+		} else {
+			fmt.Printf("Task %d is now blocked by %d\n", blocked, blocker)
+		}
+	case "import":
+		importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+		format := importCmd.String("format", "", "Format of the file (default: from its extension)")
+		importCmd.Parse(args[1:])
+		if importCmd.NArg() != 1 {
+			fmt.Println("Usage: taskcli import [--format json|todotxt|ics] file")
+			return
+		}
+		path := importCmd.Arg(0)
+		if *format == "" {
+			*format = formatForPath(path)
+		}
+		var added, updated int
+		if *format == "ics" {
+			// calendar tasks are matched by UID rather than by ID
+			file, err := os.Open(path)
+			if err != nil {
+				fmt.Println("Error reading", path+":", err)
+				return
+			}
+			tasks, added, updated, err = importICS(tasks, file)
+			file.Close()
+			if err != nil {
+				fmt.Println("Error reading", path+":", err)
+				return
+			}
+		} else {
+			imported, err := readTasksFile(path, *format)
+			if err != nil {
+				fmt.Println("Error reading", path+":", err)
+				return
+			}
+			tasks, added, updated = mergeTasks(tasks, imported)
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		fmt.Printf("Imported %s: %d added, %d updated\n", path, added, updated)
+	case "export":
+		exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+		format := exportCmd.String("format", "", "Format to write (default: from --out, else json)")
+		out := exportCmd.String("out", "", "Write to this file instead of stdout")
+		exportCmd.Parse(args[1:])
+		if *format == "" {
+			*format = formatForPath(*out)
+		}
+		codec, err := codecFor(*format)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := codec.encode(&buf, tasks); err != nil {
+			fmt.Println("Error exporting tasks:", err)
+			return
+		}
+		if *out == "" {
+			os.Stdout.Write(buf.Bytes())
+			return
+		}
+		if err := ioutil.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+			fmt.Println("Error writing", *out+":", err)
+			return
+		}
+		fmt.Printf("Exported %d tasks to %s\n", len(tasks), *out)
+	case "sync-md":
+		if len(args) != 2 {
+			fmt.Println("Usage: taskcli sync-md file.md")
+			return
+		}
+		tasks, result, err := syncMarkdown(tasks, args[1])
+		if err != nil {
+			fmt.Println("Error syncing", args[1]+":", err)
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		fmt.Printf("Synced %s: %d added, %d updated, %d completed, %d reopened, %d written back, %d unlinked\n",
+			args[1], result.added, result.updated, result.completed, result.reopened, result.written, result.unlinked)
+	case "scan":
+		scanCmd := flag.NewFlagSet("scan", flag.ExitOnError)
+		project := scanCmd.String("project", "", "Project for new tasks (default: from go.mod, package.json, ... or the directory name)")
+		scanCmd.Parse(args[1:])
+		if scanCmd.NArg() != 1 {
+			fmt.Println("Usage: taskcli scan [--project name] dir")
+			return
+		}
+		dir := scanCmd.Arg(0)
+		tasks, result, err := scanTree(tasks, dir, strings.TrimPrefix(*project, "+"))
+		if err != nil {
+			fmt.Println("Error scanning", dir+":", err)
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		fmt.Printf("Scanned %s: %d comments, %d added, %d moved, %d closed, %d reopened\n",
+			dir, result.found, result.added, result.moved, result.closed, result.reopened)
+	case "note":
+		if len(args) < 3 {
+			fmt.Println("Usage: taskcli note id text")
+			return
+		}
+		id, err := parseID(args[1])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if tasks, err = annotateTask(tasks, id, strings.Join(args[2:], " ")); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		fmt.Printf("Annotated task %d\n", id)
+	case "show":
+		if len(args) != 2 {
+			fmt.Println("Usage: taskcli show id")
+			return
+		}
+		id, err := parseID(args[1])
+		if err == nil {
+			err = showTask(tasks, id)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	case "agenda":
+		agendaCmd := flag.NewFlagSet("agenda", flag.ExitOnError)
+		days := agendaCmd.Int("days", 0, "Also show tasks due in this many days after today")
+		agendaCmd.Parse(args[1:])
+		printAgenda(tasks, *days)
+	case "board":
+		printBoard(tasks)
+	case "mv":
+		mvCmd := flag.NewFlagSet("mv", flag.ExitOnError)
+		force := mvCmd.Bool("force", false, "Move to the done status even if subtasks or blockers are open")
+		mvCmd.Parse(args[1:])
+		if mvCmd.NArg() < 2 {
+			fmt.Printf("Usage: taskcli mv [--force] ids status (statuses: %s)\n", strings.Join(config.Statuses, ", "))
+			return
+		}
+		ids, err := selectTasks(tasks, mvCmd.Args()[:1], &selectionOptions{})
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		status := mvCmd.Arg(1)
+		moved := 0
+		for _, id := range ids {
+			updated, err := moveTask(tasks, id, status, *force)
+			if err != nil {
+				fmt.Println("Error:", err)
+				var open *openDependenciesError
+				if errors.As(err, &open) {
+					fmt.Println("Not moved; use mv --force to complete it anyway")
+				}
+				continue
+			}
+			tasks = updated
+			moved++
+			fmt.Printf("Moved %d to %s: %s\n", id, status, tasks[findTask(tasks, id)].Description)
+		}
+		if moved == 0 {
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		if warning := wipWarning(tasks, status); warning != "" {
+			fmt.Println(warning)
+		}
+	case "start":
+		if len(args) < 2 {
+			fmt.Println("Usage: taskcli start id")
+			return
+		}
+		id, err := parseID(args[1])
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		tasks, stopped, err := startTimer(tasks, id)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		if stopped != 0 {
+			fmt.Printf("Stopped %d: %s\n", stopped, tasks[findTask(tasks, stopped)].Description)
+		}
+		fmt.Printf("Started %d: %s\n", id, tasks[findTask(tasks, id)].Description)
+	case "stop":
+		i := runningTask(tasks)
+		if i < 0 {
+			fmt.Println("No task is running")
+			return
+		}
+		stopTimer(tasks, i)
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		last := tasks[i].TimeEntries[len(tasks[i].TimeEntries)-1]
+		fmt.Printf("Stopped %d: %s after %s (%s in total)\n", tasks[i].ID, tasks[i].Description,
+			formatDuration(last.End.Sub(last.Start)), formatDuration(tasks[i].trackedTime()))
+	case "report":
+		reportCmd := flag.NewFlagSet("report", flag.ExitOnError)
+		week := reportCmd.Bool("week", false, "Only count time tracked this week")
+		csvPath := reportCmd.String("csv", "", "Also write the report to this CSV file")
+		reportCmd.Parse(args[1:])
+		var from, to time.Time
+		if *week {
+			from, to = thisWeek()
+			fmt.Printf("Week of %s\n", from.Format("Monday, January 2, 2006"))
+		}
+		lines := buildReport(tasks, from, to)
+		printReport(lines)
+		if *csvPath != "" {
+			var buf bytes.Buffer
+			if err := writeReportCSV(&buf, lines); err != nil {
+				fmt.Println("Error writing report:", err)
+				return
+			}
+			if err := ioutil.WriteFile(*csvPath, buf.Bytes(), 0644); err != nil {
+				fmt.Println("Error writing", *csvPath+":", err)
+				return
+			}
+			fmt.Println("Saved to:", *csvPath)
+		}
+	case "stats":
+		statsCmd := flag.NewFlagSet("stats", flag.ExitOnError)
+		weeks := statsCmd.Int("weeks", 8, "Number of weeks to count, this week included")
+		project := statsCmd.String("project", "", "Only count tasks in this project")
+		asJSON := statsCmd.Bool("json", false, "Print the stats as JSON")
+		statsCmd.Parse(args[1:])
+		if *weeks < 1 {
+			fmt.Println("Error: --weeks must be at least 1")
+			return
+		}
+		// --project is shorthand for the filter project:name
+		var scope []string
+		if *project != "" {
+			scope = append(scope, "project:"+strings.TrimPrefix(*project, "+"))
+		}
+		if expr := strings.Join(statsCmd.Args(), " "); strings.TrimSpace(expr) != "" {
+			if len(scope) > 0 {
+				expr = "(" + expr + ")"
+			}
+			scope = append(scope, expr)
+		}
+		var filter taskFilter
+		if len(scope) > 0 {
+			if filter, err = parseFilter(strings.Join(scope, " and "), config); err != nil {
+				fmt.Println("Error: invalid filter:", err)
+				return
+			}
+		}
+		all, err := statsTasks(tasks)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		report := buildStats(all, *weeks, filter)
+		report.Scope = strings.Join(scope, " and ")
+		if *asJSON {
+			if err := writeStatsJSON(report); err != nil {
+				fmt.Println("Error:", err)
+			}
+			return
+		}
+		printStats(report)
+	case "sync":
+		syncCmd := flag.NewFlagSet("sync", flag.ExitOnError)
+		server := syncCmd.String("server", config.SyncServer, "URL of the taskcli server (default: sync_server in config.json)")
+		token := syncCmd.String("token", "", "Token the server requires (default: sync_token in config.json or TASKCLI_SYNC_TOKEN)")
+		syncCmd.Parse(args[1:])
+		if *server == "" {
+			fmt.Println("Usage: taskcli sync --server URL, or set sync_server in config.json")
+			return
+		}
+		if err := checkServerURL(*server); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if *token == "" {
+			*token = config.SyncToken
+		}
+		if *token == "" {
+			*token = os.Getenv("TASKCLI_SYNC_TOKEN")
+		}
+		state, err := readSyncState(*server)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		tasks, state, report, err := syncTasks(tasks, newSyncClient(*server, *token), state)
+		if err != nil {
+			fmt.Println("Error syncing:", err)
+			return
+		}
+		if err := saveTasks(tasks); err != nil {
+			fmt.Println("Error saving tasks:", err)
+			return
+		}
+		if err := writeSyncState(state); err != nil {
+			fmt.Println("Error saving sync state:", err)
+			return
+		}
+		for _, conflict := range report.conflicts {
+			fmt.Println("Conflict:", conflict)
+		}
+		fmt.Printf("Synced with %s: %d pulled, %d pushed, %d conflict(s)\n", *server, report.pulled, report.pushed, len(report.conflicts))
+	case "undo", "redo":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				fmt.Printf("Usage: taskcli %s [number of operations]\n", action)
+				return
+			}
+		}
+		_, ops, err := undoRedo(tasks, action, n)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		verb := map[string]string{"undo": "Undone", "redo": "Redone"}[action]
+		for _, op := range ops {
+			fmt.Printf("%s: %s\n", verb, op)
+		}
+	case "log":
+		logCmd := flag.NewFlagSet("log", flag.ExitOnError)
+		n := logCmd.Int("n", 20, "Number of entries to show")
+		logCmd.Parse(args[1:])
+		if err := printLog(*n); err != nil {
+			fmt.Println("Error reading journal:", err)
+			return
+		}
 	default:
 		fmt.Println("Unknown action:", action)
 		return
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what f prints
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestNextID(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks []Task
+		want  int
+	}{
+		{name: "no tasks", tasks: nil, want: 1},
+		{name: "in order", tasks: []Task{{ID: 1}, {ID: 2}}, want: 3},
+		{name: "after the highest", tasks: []Task{{ID: 7}, {ID: 3}}, want: 8},
+		{name: "gaps are not filled", tasks: []Task{{ID: 1}, {ID: 4}}, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempTasksFile(t)
+			if got := nextID(tt.tasks); got != tt.want {
+				t.Errorf("nextID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// the ID of a removed task is not handed out again
+	useTempTasksFile(t)
+	nextID([]Task{{ID: 1}, {ID: 2}})
+	if got := nextID([]Task{{ID: 1}}); got != 4 {
+		t.Errorf("nextID() after removing 2 and 3 = %d, want 4", got)
+	}
+}
+
+func TestAssignIDs(t *testing.T) {
+	useTempTasksFile(t)
+	// tasks saved before IDs existed are numbered after the known ones
+	tasks := []Task{{Description: "a"}, {ID: 4, Description: "b"}, {Description: "c"}}
+	assignIDs(tasks)
+	var got []int
+	for _, task := range tasks {
+		got = append(got, task.ID)
+	}
+	if want := []int{5, 4, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("assignIDs() gave IDs %v, want %v", got, want)
+	}
+}
+
+func TestCompleteTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		wantErr string
+	}{
+		{name: "open task", id: 3},
+		{name: "missing task", id: 9, wantErr: "no task with ID 9"},
+		{name: "already completed", id: 2, wantErr: "already completed"},
+		{name: "invalid recurrence", id: 4, wantErr: "recur"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := []Task{{ID: 1, Description: "a"}, {ID: 2, Description: "b", Status: "done"}, {ID: 3, Description: "c"}, {ID: 4, Description: "d", Recur: "every blue moon"}}
+			got, err := completeTask(tasks, tt.id)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("completeTask() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				// a failed completion leaves the task as it was
+				if tasks[3].isCompleted() || tasks[3].CompletedAt != nil {
+					t.Errorf("completeTask() changed the task it failed on: %+v", tasks[3])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("completeTask() error = %v", err)
+			}
+			if i := findTask(got, tt.id); i < 0 || !got[i].isCompleted() {
+				t.Errorf("task %d not completed: %+v", tt.id, got)
+			}
+			// the other tasks keep their numbers
+			if len(got) != 4 || got[0].ID != 1 || got[1].ID != 2 || got[0].isCompleted() {
+				t.Errorf("completeTask() changed other tasks: %+v", got)
+			}
+		})
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    int
+		wantErr bool
+	}{
+		{arg: "1", want: 1},
+		{arg: "42", want: 42},
+		{arg: "0", wantErr: true},
+		{arg: "-3", wantErr: true},
+		{arg: "two", wantErr: true},
+		{arg: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseID(tt.arg)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseID(%q) = %d, %v, want %d, error %v", tt.arg, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveAndLoadTasks(t *testing.T) {
+	useTempTasksFile(t)
+
+	tasks, err := loadTasks()
+	if err != nil || len(tasks) != 0 {
+		t.Fatalf("loadTasks() without a file = %v, %v, want no tasks", tasks, err)
+	}
+
+	tasks = addTask(tasks, "Write report")
+	tasks = addTask(tasks, "Send report")
+	tasks = addTask(tasks, "File expenses")
+	if tasks, err = completeTask(tasks, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveTasks(tasks); err != nil {
+		t.Fatalf("saveTasks() error = %v", err)
+	}
+	loaded, err := loadTasks()
+	if err != nil {
+		t.Fatalf("loadTasks() error = %v", err)
+	}
+	if len(loaded) != len(tasks) {
+		t.Fatalf("loadTasks() = %+v, want %+v", loaded, tasks)
+	}
+	for i := range tasks {
+		if loaded[i].ID != tasks[i].ID || loaded[i].Description != tasks[i].Description || loaded[i].Status != tasks[i].Status {
+			t.Errorf("loaded task %d = %+v, want %+v", i, loaded[i], tasks[i])
+		}
+	}
+
+	// a file from before IDs existed, read by a new run
+	highestID = 0
+	legacy := `[{"description": "Old task", "completed": false}, {"description": "Older task", "completed": true}]`
+	if err := os.WriteFile(tasksFile, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = loadTasks()
+	if err != nil {
+		t.Fatalf("loadTasks() error = %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].ID != 1 || loaded[1].ID != 2 || !loaded[1].isCompleted() {
+		t.Errorf("loadTasks() of a legacy file = %+v, want IDs 1 and 2", loaded)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Markdown checklists: sync-md imports "- [ ] item" and "- [x] item" lines
+// as tasks linked to their file and line. Completing or reopening such a
+// task ticks or unticks its box in the file; nothing else in the file is
+// touched.
+
+var checklistPattern = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)([ xX])\]\s+(.*?)\s*$`)
+
+// checklistItem is one checklist line of a markdown file
+type checklistItem struct {
+	line    int // 1-based
+	box     int // byte offset of the character between the brackets
+	checked bool
+	text    string
+}
+
+// markdownFile is a markdown file split into lines, kept byte for byte
+type markdownFile struct {
+	path  string
+	lines []string
+	items []checklistItem
+}
+
+func readMarkdownFile(path string) (*markdownFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	md := &markdownFile{path: path, lines: strings.SplitAfter(string(data), "\n")}
+	fence := ""
+	for i, line := range md.lines {
+		trimmed := strings.TrimSpace(line)
+		// checkboxes in code blocks are examples, not tasks
+		if fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")) {
+			fence = trimmed[:3]
+			continue
+		}
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		m := checklistPattern.FindStringSubmatchIndex(strings.TrimRight(line, "\r\n"))
+		if m == nil || m[7] == m[6] {
+			continue
+		}
+		md.items = append(md.items, checklistItem{
+			line:    i + 1,
+			box:     m[4],
+			checked: line[m[4]] != ' ',
+			text:    line[m[6]:m[7]],
+		})
+	}
+	return md, nil
+}
+
+// setChecked ticks or unticks the item on a line, reporting whether the
+// file changed
+func (md *markdownFile) setChecked(item *checklistItem, checked bool) bool {
+	if item.checked == checked {
+		return false
+	}
+	mark := " "
+	if checked {
+		mark = "x"
+	}
+	line := md.lines[item.line-1]
+	md.lines[item.line-1] = line[:item.box] + mark + line[item.box+1:]
+	item.checked = checked
+	return true
+}
+
+func (md *markdownFile) write() error {
+	return writeFileAtomic(md.path, []byte(strings.Join(md.lines, "")))
+}
+
+// locate finds the item a source points at: the one on its line if the
+// text still matches, else the matching item nearest to it
+func (md *markdownFile) locate(src taskSource, taken map[int]bool) *checklistItem {
+	var best *checklistItem
+	for i := range md.items {
+		item := &md.items[i]
+		if taken[item.line] || item.text != src.Text {
+			continue
+		}
+		if best == nil || abs(item.line-src.Line) < abs(best.line-src.Line) {
+			best = item
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// markdownSync counts what a sync-md run did
+type markdownSync struct {
+	added, updated, completed, reopened, written, unlinked int
+}
+
+// syncMarkdown brings a markdown file and its tasks in line. A box ticked
+// or unticked in the file since the last sync completes or reopens the
+// task; otherwise the task's state is written to the box. New items become
+// tasks, and tasks whose item is gone are unlinked from the file.
+func syncMarkdown(tasks []Task, path string) ([]Task, markdownSync, error) {
+	var result markdownSync
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, result, err
+	}
+	md, err := readMarkdownFile(path)
+	if err != nil {
+		return nil, result, err
+	}
+
+	var linked []int
+	for i, task := range tasks {
+		if task.Source != nil && task.Source.Kind == sourceMarkdown && task.Source.File == path {
+			linked = append(linked, i)
+		}
+	}
+	sort.Slice(linked, func(a, b int) bool { return tasks[linked[a]].Source.Line < tasks[linked[b]].Source.Line })
+
+	// match tasks to items by text first, then items whose text was
+	// edited by their line
+	matches := map[int]*checklistItem{}
+	taken := map[int]bool{}
+	for _, i := range linked {
+		if item := md.locate(*tasks[i].Source, taken); item != nil {
+			matches[i] = item
+			taken[item.line] = true
+		}
+	}
+	for _, i := range linked {
+		if matches[i] != nil {
+			continue
+		}
+		for j := range md.items {
+			if item := &md.items[j]; item.line == tasks[i].Source.Line && !taken[item.line] {
+				matches[i] = item
+				taken[item.line] = true
+			}
+		}
+	}
+
+	changed := false
+	for _, i := range linked {
+		item := matches[i]
+		if item == nil {
+			tasks[i].Source = nil
+			result.unlinked++
+			continue
+		}
+		src := tasks[i].Source
+		if item.text != src.Text {
+			tasks[i].Description = item.text
+			parseDescription(&tasks[i])
+			result.updated++
+		}
+		src.Line, src.Text = item.line, item.text
+
+		id := tasks[i].ID
+		switch {
+		case item.checked != src.Checked && item.checked && !tasks[i].isCompleted():
+			if tasks, err = completeTask(tasks, id); err != nil {
+				return nil, result, err
+			}
+			result.completed++
+		case item.checked != src.Checked && !item.checked && tasks[i].isCompleted():
+			if tasks, err = reopenTask(tasks, id); err != nil {
+				return nil, result, err
+			}
+			result.reopened++
+		case item.checked == src.Checked && md.setChecked(item, tasks[i].isCompleted()):
+			changed = true
+			result.written++
+		}
+		src.Checked = item.checked
+	}
+
+	for _, item := range md.items {
+		if taken[item.line] {
+			continue
+		}
+		tasks = addTask(tasks, item.text)
+		n := len(tasks) - 1
+		tasks[n].Source = &taskSource{Kind: sourceMarkdown, File: path, Line: item.line, Text: item.text, Checked: item.checked}
+		if item.checked {
+			completedAt := *tasks[n].CreatedAt
+			tasks[n].Status = config.doneStatus()
+			tasks[n].CompletedAt = &completedAt
+		}
+		result.added++
+	}
+
+	if changed {
+		if err := md.write(); err != nil {
+			return nil, result, err
+		}
+	}
+	return tasks, result, nil
+}
+
+// writeBackMarkdown ticks or unticks the boxes of linked tasks that were
+// completed or reopened since the tasks were loaded. Files that are gone
+// or no longer have the item are skipped with a warning; the next sync-md
+// sorts them out.
+func writeBackMarkdown(before, after []Task) {
+	files := map[string][]int{}
+	for i, task := range after {
+		src := task.Source
+		if src == nil || src.Kind != sourceMarkdown {
+			continue
+		}
+		j := findTask(before, task.ID)
+		if task.isCompleted() != src.Checked || j >= 0 && before[j].isCompleted() != task.isCompleted() {
+			files[src.File] = append(files[src.File], i)
+		}
+	}
+
+	for path, indexes := range files {
+		md, err := readMarkdownFile(path)
+		if err != nil {
+			fmt.Println("Warning: not updating checklist:", err)
+			continue
+		}
+		changed := false
+		for _, i := range indexes {
+			src := after[i].Source
+			item := md.locate(*src, map[int]bool{})
+			if item == nil {
+				fmt.Printf("Warning: %q is no longer in %s\n", src.Text, path)
+				continue
+			}
+			if md.setChecked(item, after[i].isCompleted()) {
+				changed = true
+			}
+			src.Line, src.Checked = item.line, item.checked
+		}
+		if !changed {
+			continue
+		}
+		if err := md.write(); err != nil {
+			fmt.Println("Warning: not updating checklist:", err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const checklist = `# Release
+
+- [ ] Write changelog +docs
+- [x] Tag the release
+* [ ] Announce it
+
+` + "```" + `
+- [ ] not a task, an example
+` + "```" + `
+1. [ ] Numbered item
+- [ ]
+`
+
+// writeChecklist writes a markdown file and returns its path
+func writeChecklist(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "release.md")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readChecklist(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// syncChecklist runs syncMarkdown and fails the test on an error
+func syncChecklist(t *testing.T, tasks []Task, path string) ([]Task, markdownSync) {
+	t.Helper()
+	tasks, result, err := syncMarkdown(tasks, path)
+	if err != nil {
+		t.Fatalf("syncMarkdown() error = %v", err)
+	}
+	return tasks, result
+}
+
+func taskByDescription(t *testing.T, tasks []Task, description string) Task {
+	t.Helper()
+	for _, task := range tasks {
+		if task.Description == description {
+			return task
+		}
+	}
+	t.Fatalf("no task %q in %+v", description, tasks)
+	return Task{}
+}
+
+func TestReadMarkdownFile(t *testing.T) {
+	md, err := readMarkdownFile(writeChecklist(t, checklist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []checklistItem{
+		{line: 3, box: 3, text: "Write changelog +docs"},
+		{line: 4, box: 3, checked: true, text: "Tag the release"},
+		{line: 5, box: 3, text: "Announce it"},
+		{line: 10, box: 4, text: "Numbered item"},
+	}
+	if len(md.items) != len(want) {
+		t.Fatalf("items = %+v, want %+v", md.items, want)
+	}
+	for i := range want {
+		if md.items[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, md.items[i], want[i])
+		}
+	}
+}
+
+func TestSyncMarkdownImports(t *testing.T) {
+	path := writeChecklist(t, checklist)
+	tasks, result := syncChecklist(t, []Task{{ID: 1, Description: "Unrelated", Status: "todo"}}, path)
+	if result.added != 4 || len(tasks) != 5 {
+		t.Fatalf("first sync added %d, tasks %+v, want 4 added", result.added, tasks)
+	}
+	tagged := taskByDescription(t, tasks, "Tag the release")
+	if !tagged.isCompleted() || tagged.Source == nil || tagged.Source.Line != 4 || !tagged.Source.Checked {
+		t.Errorf("checked item imported as %+v, want a completed task linked to line 4", tagged)
+	}
+	if docs := taskByDescription(t, tasks, "Write changelog +docs"); docs.isCompleted() || len(docs.Projects) != 1 {
+		t.Errorf("open item imported as %+v", docs)
+	}
+
+	// a second sync of the same file changes nothing
+	tasks, result = syncChecklist(t, tasks, path)
+	if result != (markdownSync{}) || len(tasks) != 5 || readChecklist(t, path) != checklist {
+		t.Errorf("second sync = %+v with %d tasks, want no changes", result, len(tasks))
+	}
+}
+
+func TestSyncMarkdownTickedInFile(t *testing.T) {
+	path := writeChecklist(t, checklist)
+	tasks, _ := syncChecklist(t, nil, path)
+	id := taskByDescription(t, tasks, "Write changelog +docs").ID
+
+	// ticking the box in an editor completes the task
+	ticked := strings.Replace(checklist, "- [ ] Write changelog", "- [X] Write changelog", 1)
+	os.WriteFile(path, []byte(ticked), 0o644)
+	tasks, result := syncChecklist(t, tasks, path)
+	if task := tasks[findTask(tasks, id)]; result.completed != 1 || !task.isCompleted() {
+		t.Errorf("sync after ticking = %+v, task %+v, want it completed", result, task)
+	}
+	if got := readChecklist(t, path); got != ticked {
+		t.Errorf("sync rewrote the file:\n%s", got)
+	}
+
+	// and unticking it reopens it
+	os.WriteFile(path, []byte(checklist), 0o644)
+	tasks, result = syncChecklist(t, tasks, path)
+	if task := tasks[findTask(tasks, id)]; result.reopened != 1 || task.isCompleted() {
+		t.Errorf("sync after unticking = %+v, task %+v, want it reopened", result, task)
+	}
+}
+
+func TestMarkdownCompletingTicksBox(t *testing.T) {
+	path := writeChecklist(t, checklist)
+	tasks, _ := syncChecklist(t, nil, path)
+	announce := taskByDescription(t, tasks, "Announce it").ID
+	tag := taskByDescription(t, tasks, "Tag the release").ID
+
+	// completing and reopening through taskcli, as saveTasks writes back
+	before := mustCloneTasks(t, tasks)
+	tasks, err := completeTask(tasks, announce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks, err = reopenTask(tasks, tag); err != nil {
+		t.Fatal(err)
+	}
+	writeBackMarkdown(before, tasks)
+	want := strings.Replace(checklist, "* [ ] Announce", "* [x] Announce", 1)
+	want = strings.Replace(want, "- [x] Tag", "- [ ] Tag", 1)
+	if got := readChecklist(t, path); got != want {
+		t.Errorf("file after completing and reopening =\n%s\nwant\n%s", got, want)
+	}
+	if src := tasks[findTask(tasks, announce)].Source; !src.Checked {
+		t.Errorf("source of the completed task = %+v, want it checked", src)
+	}
+
+	// a sync also writes a state changed without the write-back
+	numbered := taskByDescription(t, tasks, "Numbered item").ID
+	if tasks, err = completeTask(tasks, numbered); err != nil {
+		t.Fatal(err)
+	}
+	tasks, result := syncChecklist(t, tasks, path)
+	want = strings.Replace(want, "1. [ ] Numbered", "1. [x] Numbered", 1)
+	if got := readChecklist(t, path); result.written != 1 || got != want {
+		t.Errorf("sync wrote %d boxes, file =\n%s\nwant\n%s", result.written, got, want)
+	}
+	if !tasks[findTask(tasks, numbered)].isCompleted() {
+		t.Errorf("sync reopened the task it should have ticked")
+	}
+}
+
+func TestSyncMarkdownFollowsEditedLines(t *testing.T) {
+	path := writeChecklist(t, checklist)
+	tasks, _ := syncChecklist(t, nil, path)
+	docs := taskByDescription(t, tasks, "Write changelog +docs").ID
+	announce := taskByDescription(t, tasks, "Announce it").ID
+
+	// lines added above move the items
+	moved := strings.Replace(checklist, "# Release\n", "# Release\n\nIntro.\n- [ ] Brand new\n", 1)
+	os.WriteFile(path, []byte(moved), 0o644)
+	tasks, result := syncChecklist(t, tasks, path)
+	if result.added != 1 || result.updated != 0 || result.unlinked != 0 {
+		t.Errorf("sync after moving = %+v, want 1 added", result)
+	}
+	if src := tasks[findTask(tasks, docs)].Source; src.Line != 6 {
+		t.Errorf("moved item linked at line %d, want 6", src.Line)
+	}
+
+	// an item edited in place keeps its task, with the new text
+	edited := strings.Replace(moved, "Announce it", "Announce it on the blog", 1)
+	os.WriteFile(path, []byte(edited), 0o644)
+	tasks, result = syncChecklist(t, tasks, path)
+	if result.added != 0 || result.updated != 1 || result.unlinked != 0 {
+		t.Errorf("sync after editing = %+v, want 1 updated", result)
+	}
+	task := tasks[findTask(tasks, announce)]
+	if task.Description != "Announce it on the blog" || task.Source == nil || task.Source.Line != 8 {
+		t.Fatalf("edited item = %+v, want the new text at line 8", task)
+	}
+
+	// completing the moved task ticks its box at the new line
+	before := mustCloneTasks(t, tasks)
+	tasks, _ = completeTask(tasks, docs)
+	writeBackMarkdown(before, tasks)
+	if got := readChecklist(t, path); got != strings.Replace(edited, "[ ] Write changelog", "[x] Write changelog", 1) {
+		t.Errorf("ticked the wrong box:\n%s", got)
+	}
+}
+
+func TestSyncMarkdownDeletedLine(t *testing.T) {
+	path := writeChecklist(t, checklist)
+	tasks, _ := syncChecklist(t, nil, path)
+	announce := taskByDescription(t, tasks, "Announce it").ID
+
+	deleted := strings.Replace(checklist, "* [ ] Announce it\n", "", 1)
+	os.WriteFile(path, []byte(deleted), 0o644)
+	tasks, result := syncChecklist(t, tasks, path)
+	task := tasks[findTask(tasks, announce)]
+	if result.unlinked != 1 || task.Source != nil || task.isCompleted() {
+		t.Errorf("sync after deleting = %+v, task %+v, want it unlinked and kept", result, task)
+	}
+
+	// completing an item whose line is gone leaves the file alone
+	tasks, _ = syncChecklist(t, nil, path)
+	docs := taskByDescription(t, tasks, "Write changelog +docs").ID
+	os.WriteFile(path, []byte(strings.Replace(deleted, "- [ ] Write changelog +docs\n", "", 1)), 0o644)
+	before := mustCloneTasks(t, tasks)
+	tasks, _ = completeTask(tasks, docs)
+	out := captureStdout(t, func() { writeBackMarkdown(before, tasks) })
+	if !strings.Contains(out, "no longer in") {
+		t.Errorf("write-back printed %q, want a warning", out)
+	}
+	if got := readChecklist(t, path); got != strings.Replace(deleted, "- [ ] Write changelog +docs\n", "", 1) {
+		t.Errorf("write-back changed the file:\n%s", got)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// annotation is a timestamped remark added to a task with note
+type annotation struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// extractLinks collects the URLs in a task's description, annotations and
+// notes, in that order and without duplicates
+func extractLinks(task Task) []string {
+	texts := []string{task.Description}
+	for _, a := range task.Annotations {
+		texts = append(texts, a.Text)
+	}
+	texts = append(texts, task.Notes)
+
+	var links []string
+	seen := map[string]bool{}
+	for _, text := range texts {
+		for _, link := range linkPattern.FindAllString(text, -1) {
+			// punctuation after a URL usually ends the sentence
+			link = strings.TrimRight(link, ".,;:!?)]")
+			if !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// annotateTask adds a timestamped annotation to a task
+func annotateTask(tasks []Task, id int, text string) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	now := time.Now().Truncate(time.Second)
+	tasks[i].Annotations = append(tasks[i].Annotations, annotation{Time: now, Text: text})
+	parseDescription(&tasks[i])
+	return tasks, nil
+}
+
+// setNotes replaces a task's notes body
+func setNotes(tasks []Task, id int, notes string) ([]Task, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("no task with ID %d", id)
+	}
+	tasks[i].Notes = notes
+	parseDescription(&tasks[i])
+	return tasks, nil
+}
+
+// editNotes opens the task's notes in $VISUAL or $EDITOR and saves what
+// comes back. The lock is not held while the editor is open, only to read
+// the notes and to store them.
+func editNotes(id int) error {
+	tasks, err := readTasks()
+	if err != nil {
+		return err
+	}
+	i := findTask(tasks, id)
+	if i < 0 {
+		return fmt.Errorf("no task with ID %d", id)
+	}
+
+	file, err := os.CreateTemp("", fmt.Sprintf("taskcli-%d-*.md", id))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(tasks[i].Notes)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// the editor may come with arguments, e.g. "code --wait"
+	words := strings.Fields(editor)
+	cmd := exec.Command(words[0], append(words[1:], file.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	notes := strings.TrimRight(string(data), "\n")
+	if notes == tasks[i].Notes {
+		fmt.Println("Notes unchanged")
+		return nil
+	}
+	if _, err := updateTasks(fmt.Sprintf("notes %d", id), func(tasks []Task) ([]Task, error) {
+		return setNotes(tasks, id, notes)
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Notes of task %d saved\n", id)
+	return nil
+}
+
+// fieldChanges lists the JSON fields that differ between two versions of
+// a task, with short values shown inline
+func fieldChanges(before, after Task) []string {
+	var b, a map[string]any
+	beforeData, _ := json.Marshal(before)
+	afterData, _ := json.Marshal(after)
+	json.Unmarshal(beforeData, &b)
+	json.Unmarshal(afterData, &a)
+
+	keys := map[string]bool{}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range a {
+		keys[k] = true
+	}
+	var names []string
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	show := func(v any) string {
+		if v == nil {
+			return "none"
+		}
+		data, _ := json.Marshal(v)
+		return truncate(strings.Trim(string(data), `"`), 40)
+	}
+	var changes []string
+	for _, k := range names {
+		// every change sets it, so it would only repeat the entry's time
+		if k == "modified_at" {
+			continue
+		}
+		bv, _ := json.Marshal(b[k])
+		av, _ := json.Marshal(a[k])
+		if string(bv) == string(av) {
+			continue
+		}
+		if isScalar(b[k]) && isScalar(a[k]) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", k, show(b[k]), show(a[k])))
+			continue
+		}
+		changes = append(changes, k+" changed")
+	}
+	return changes
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case []any, map[string]any:
+		return false
+	}
+	return true
+}
+
+// showTask prints everything about a task: its fields, notes, links,
+// annotations and the journaled operations that changed it
+func showTask(tasks []Task, id int) error {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return fmt.Errorf("no task with ID %d", id)
+	}
+	task := tasks[i]
+
+	fmt.Println(formatTask(task))
+	field := func(name, value string) { fmt.Printf("  %-11s %s\n", name+":", value) }
+	field("status", task.Status)
+	if task.CreatedAt != nil {
+		field("created", task.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	if task.CompletedAt != nil {
+		field("completed", task.CompletedAt.Format("2006-01-02 15:04"))
+	}
+	if task.ModifiedAt != nil {
+		field("modified", task.ModifiedAt.Format("2006-01-02 15:04"))
+	}
+	if task.Parent != 0 {
+		field("parent", fmt.Sprint(task.Parent))
+	}
+	if children := childrenOf(tasks, id); len(children) > 0 {
+		field("subtasks", formatIDs(children))
+	}
+	if len(task.BlockedBy) > 0 {
+		field("blocked by", formatIDs(task.BlockedBy))
+	}
+	if len(task.TimeEntries) > 0 {
+		field("tracked", formatDuration(task.trackedTime()))
+	}
+	if task.Source != nil {
+		field("source", fmt.Sprintf("%s:%d", task.Source.File, task.Source.Line))
+	}
+	for _, link := range task.Links {
+		field("link", link)
+	}
+
+	if task.Notes != "" {
+		fmt.Println("\nNotes:")
+		for _, line := range strings.Split(task.Notes, "\n") {
+			fmt.Println("  " + line)
+		}
+	}
+	if len(task.Annotations) > 0 {
+		fmt.Println("\nAnnotations:")
+		for _, a := range task.Annotations {
+			fmt.Printf("  %s  %s\n", a.Time.Format("2006-01-02 15:04"), a.Text)
+		}
+	}
+
+	entries, err := readJournal()
+	if err != nil {
+		return err
+	}
+	var history []string
+	for _, entry := range entries {
+		for _, change := range entry.Changes {
+			if change.ID != id {
+				continue
+			}
+			op := entry.Op
+			if entry.Kind != journalChange {
+				op = fmt.Sprintf("%s #%d (%s)", entry.Kind, entry.Ref, entry.Op)
+			}
+			var what string
+			switch {
+			case change.Before == nil:
+				what = "added"
+			case change.After == nil:
+				what = "removed"
+			default:
+				what = strings.Join(fieldChanges(*change.Before, *change.After), ", ")
+			}
+			history = append(history, fmt.Sprintf("  %s  %-30s %s", entry.Time.Format("2006-01-02 15:04"), op, what))
+		}
+	}
+	if len(history) > 0 {
+		fmt.Println("\nHistory:")
+		for _, line := range history {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractLinks(t *testing.T) {
+	task := Task{
+		Description: "Review https://example.com/pr/1, then merge",
+		Annotations: []annotation{
+			{Text: "see (https://example.com/issue?id=2) and https://example.com/pr/1."},
+			{Text: "no link here"},
+		},
+		Notes: "Docs: http://docs.example.com/a#b\n<https://example.com/c>\n\"https://example.com/d\"",
+	}
+	want := []string{
+		"https://example.com/pr/1",
+		"https://example.com/issue?id=2",
+		"http://docs.example.com/a#b",
+		"https://example.com/c",
+		"https://example.com/d",
+	}
+	if got := extractLinks(task); !reflect.DeepEqual(got, want) {
+		t.Errorf("extractLinks() =\n%q\nwant\n%q", got, want)
+	}
+	if got := extractLinks(Task{Description: "ftp://example.com and example.com"}); got != nil {
+		t.Errorf("extractLinks() without http links = %q, want none", got)
+	}
+}
+
+func TestAnnotateTask(t *testing.T) {
+	tasks := []Task{{ID: 1, Description: "Fix login"}}
+	tasks, err := annotateTask(tasks, 1, "tried https://example.com/fix")
+	if err != nil {
+		t.Fatalf("annotateTask() error = %v", err)
+	}
+	if tasks, err = annotateTask(tasks, 1, "still broken"); err != nil {
+		t.Fatalf("annotateTask() error = %v", err)
+	}
+	got := tasks[0]
+	if len(got.Annotations) != 2 || got.Annotations[1].Text != "still broken" || got.Annotations[0].Time.IsZero() {
+		t.Errorf("annotations = %+v", got.Annotations)
+	}
+	if !reflect.DeepEqual(got.Links, []string{"https://example.com/fix"}) {
+		t.Errorf("links after annotating = %q", got.Links)
+	}
+
+	if tasks, err = setNotes(tasks, 1, "Steps:\n1. open https://example.com/login"); err != nil {
+		t.Fatalf("setNotes() error = %v", err)
+	}
+	if want := []string{"https://example.com/fix", "https://example.com/login"}; !reflect.DeepEqual(tasks[0].Links, want) {
+		t.Errorf("links after setting notes = %q, want %q", tasks[0].Links, want)
+	}
+
+	if _, err := annotateTask(tasks, 2, "x"); err == nil {
+		t.Errorf("annotateTask(2) succeeded for a missing task")
+	}
+	if _, err := setNotes(tasks, 2, "x"); err == nil {
+		t.Errorf("setNotes(2) succeeded for a missing task")
+	}
+}
+
+func TestNotesRoundTrip(t *testing.T) {
+	task := Task{
+		ID:          3,
+		Description: "Call Bob",
+		Status:      config.initialStatus(),
+		Annotations: []annotation{
+			{Time: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC), Text: "tried, no answer | 50% done"},
+			{Time: time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC), Text: "left a message; call back https://example.com"},
+		},
+		Notes: "Number: +1 555 0100\n\nAsk about: the invoice, the #ui mockups",
+	}
+	parseDescription(&task)
+
+	for _, tt := range []struct {
+		name  string
+		codec taskCodec
+	}{
+		{name: "json", codec: jsonCodec{}},
+		{name: "todo.txt", codec: todoTxtCodec{}},
+		{name: "ics", codec: icsCodec{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoded := roundTrip(t, tt.codec, []Task{task})
+			if len(got) != 1 {
+				t.Fatalf("got %d tasks\n%s", len(got), encoded)
+			}
+			if got[0].Notes != task.Notes || !reflect.DeepEqual(got[0].Links, task.Links) || len(got[0].Annotations) != 2 {
+				t.Fatalf("round trip = %+v\n%s", got[0], encoded)
+			}
+			for i, a := range got[0].Annotations {
+				if want := task.Annotations[i]; a.Text != want.Text || !a.Time.Equal(want.Time) {
+					t.Errorf("annotation %d = %+v, want %+v", i, a, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldChanges(t *testing.T) {
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	before := Task{ID: 1, Description: "Fix login +web", Status: "todo", Priority: "B"}
+	parseDescription(&before)
+	after := before
+	after.Description = "Fix signup +api"
+	parseDescription(&after)
+	after.Priority = ""
+	after.Due = &due
+
+	want := []string{
+		"description: Fix login +web -> Fix signup +api",
+		"due: none -> 2026-10-20T00:00:00Z",
+		"priority: B -> none",
+		"projects changed",
+	}
+	if got := fieldChanges(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("fieldChanges() =\n%q\nwant\n%q", got, want)
+	}
+	if got := fieldChanges(before, before); got != nil {
+		t.Errorf("fieldChanges() of an unchanged task = %q", got)
+	}
+}
+
+func TestShowTask(t *testing.T) {
+	useTempTasksFile(t)
+	operation = "add Fix login"
+	tasks := addTask(nil, "Fix login")
+	if err := saveTasks(tasks); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := updateTasks("note 1 tried https://example.com", func(tasks []Task) ([]Task, error) {
+		return annotateTask(tasks, 1, "tried https://example.com")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := updateTasks("prio 1 A", func(tasks []Task) ([]Task, error) {
+		return setPriority(tasks, 1, "A")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := showTask(tasks, 1); err != nil {
+			t.Errorf("showTask() error = %v", err)
+		}
+	})
+	for _, want := range []string{
+		"status:     todo",
+		"link:       https://example.com",
+		"Annotations:",
+		"tried https://example.com",
+		"History:",
+		"add Fix login                  added",
+		"annotations changed",
+		"prio 1 A                       priority: none -> A",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("showTask() output lacks %q:\n%s", want, out)
+		}
+	}
+	if err := showTask(tasks, 2); err == nil {
+		t.Errorf("showTask(2) succeeded for a missing task")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// priorities from highest to lowest, as single letters like todo.txt
+var priorities = []string{"A", "B", "C", "D"}
+
+var priorityNames = map[string]string{
+	"high":   "A",
+	"medium": "B",
+	"low":    "C",
+}
+
+// parsePriority accepts a letter A-D or high/medium/low and returns the letter
+func parsePriority(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if letter, ok := priorityNames[strings.ToLower(s)]; ok {
+		return letter, nil
+	}
+	upper := strings.ToUpper(s)
+	for _, p := range priorities {
+		if upper == p {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid priority %q: use A-D or high/medium/low", s)
+}
+
+// priorityRank orders priorities for sorting; tasks without one sort last
+func priorityRank(p string) int {
+	for i, letter := range priorities {
+		if p == letter {
+			return i
+		}
+	}
+	return len(priorities)
+}
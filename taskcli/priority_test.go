@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "A", want: "A"},
+		{input: "d", want: "D"},
+		{input: "high", want: "A"},
+		{input: "Medium", want: "B"},
+		{input: " low ", want: "C"},
+		{input: "E", wantErr: true},
+		{input: "urgent", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parsePriority(tt.input)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parsePriority(%q) = %q, %v, want %q, error %v", tt.input, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPriorityRank(t *testing.T) {
+	// higher priorities rank first and no priority ranks last
+	order := []string{"A", "B", "C", "D", ""}
+	for i := 1; i < len(order); i++ {
+		if priorityRank(order[i-1]) >= priorityRank(order[i]) {
+			t.Errorf("priorityRank(%q) = %d, not before priorityRank(%q) = %d",
+				order[i-1], priorityRank(order[i-1]), order[i], priorityRank(order[i]))
+		}
+	}
+}
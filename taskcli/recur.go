@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrence is a parsed recurrence rule such as "every 2 weeks"
+type recurrence struct {
+	unit     string        // day, week, month or year
+	interval int           // every interval units
+	weekday  *time.Weekday // set for "every monday"
+	monthDay int           // set for "monthly on the 1st"
+	day      int           // day of the month "every month" and "every year" fall on
+}
+
+var (
+	everyPattern     = regexp.MustCompile(`^every (?:(\d+) )?(day|week|month|year)s?$`)
+	shortPattern     = regexp.MustCompile(`^\+?(\d+)([dwmy])$`)
+	monthDayPattern  = regexp.MustCompile(`^(?:monthly|every month) on the (\d{1,2})(?:st|nd|rd|th)?$`)
+	recurrenceUnits  = map[string]string{"d": "day", "w": "week", "m": "month", "y": "year"}
+	recurrenceSyntax = `use "every day", "every 2 weeks", "every monday", "monthly on the 1st" or 2w`
+)
+
+// parseRecurrence understands "every [N] day|week|month|year[s]",
+// "every <weekday>", "monthly on the Nth" and the todo.txt shorthand Nd,
+// Nw, Nm and Ny
+func parseRecurrence(s string) (recurrence, error) {
+	rule := strings.Join(strings.Fields(strings.ToLower(s)), " ")
+
+	if m := everyPattern.FindStringSubmatch(rule); m != nil {
+		interval := 1
+		if m[1] != "" {
+			interval, _ = strconv.Atoi(m[1])
+		}
+		if interval < 1 {
+			return recurrence{}, fmt.Errorf("invalid recurrence %q: interval must be at least 1", s)
+		}
+		return recurrence{unit: m[2], interval: interval}, nil
+	}
+	if m := shortPattern.FindStringSubmatch(rule); m != nil {
+		interval, _ := strconv.Atoi(m[1])
+		if interval < 1 {
+			return recurrence{}, fmt.Errorf("invalid recurrence %q: interval must be at least 1", s)
+		}
+		return recurrence{unit: recurrenceUnits[m[2]], interval: interval}, nil
+	}
+	if m := monthDayPattern.FindStringSubmatch(rule); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		if day < 1 || day > 31 {
+			return recurrence{}, fmt.Errorf("invalid recurrence %q: day of month must be 1-31", s)
+		}
+		return recurrence{unit: "month", interval: 1, monthDay: day}, nil
+	}
+	if name, ok := strings.CutPrefix(rule, "every "); ok {
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if name == strings.ToLower(wd.String()) {
+				weekday := wd
+				return recurrence{unit: "week", interval: 1, weekday: &weekday}, nil
+			}
+		}
+	}
+	return recurrence{}, fmt.Errorf("invalid recurrence %q: %s", s, recurrenceSyntax)
+}
+
+// daysIn returns the number of days in a month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local).Day()
+}
+
+// onMonthDay returns the given day in t's month, clamped to the month's
+// last day so "on the 31st" falls on the 30th in April
+func onMonthDay(t time.Time, day int) time.Time {
+	if last := daysIn(t.Year(), t.Month()); day > last {
+		day = last
+	}
+	return time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, time.Local)
+}
+
+// anchored reports whether the rule falls on a day of the month it takes
+// from the due date, which short months move earlier
+func (r recurrence) anchored() bool {
+	return r.weekday == nil && r.monthDay == 0 && (r.unit == "month" || r.unit == "year")
+}
+
+// after returns the first occurrence strictly after the given day.
+// Monthly and yearly rules fall on r.day, or on the given day's day of the
+// month if r.day is not set.
+func (r recurrence) after(t time.Time) time.Time {
+	day := startOfDay(t)
+	anchor := day.Day()
+	if r.day != 0 {
+		anchor = r.day
+	}
+	switch {
+	case r.weekday != nil:
+		offset := (int(*r.weekday) - int(day.Weekday()) + 7) % 7
+		if offset == 0 {
+			offset = 7
+		}
+		return day.AddDate(0, 0, offset)
+	case r.monthDay != 0:
+		next := onMonthDay(day, r.monthDay)
+		if !next.After(day) {
+			first := time.Date(day.Year(), day.Month()+1, 1, 0, 0, 0, 0, time.Local)
+			next = onMonthDay(first, r.monthDay)
+		}
+		return next
+	case r.unit == "day":
+		return day.AddDate(0, 0, r.interval)
+	case r.unit == "week":
+		return day.AddDate(0, 0, 7*r.interval)
+	case r.unit == "month":
+		first := time.Date(day.Year(), day.Month()+time.Month(r.interval), 1, 0, 0, 0, 0, time.Local)
+		return onMonthDay(first, anchor)
+	default:
+		first := time.Date(day.Year()+r.interval, day.Month(), 1, 0, 0, 0, 0, time.Local)
+		return onMonthDay(first, anchor)
+	}
+}
+
+// first returns the first occurrence on or after the given day
+func (r recurrence) first(t time.Time) time.Time {
+	if r.weekday == nil && r.monthDay == 0 {
+		return startOfDay(t)
+	}
+	return r.after(startOfDay(t).AddDate(0, 0, -1))
+}
+
+// nextOccurrence builds the task that replaces a completed recurring task.
+// Its due date follows the schedule from the old due date, skipping
+// occurrences that are already in the past. Monthly and yearly rules keep
+// the day of the month they started on, so a task due on the 31st is due
+// on the 28th in February and on the 31st again in March.
+func nextOccurrence(tasks []Task, done Task) (Task, error) {
+	rule, err := parseRecurrence(done.Recur)
+	if err != nil {
+		return Task{}, err
+	}
+
+	base := today()
+	if done.Due != nil {
+		base = *done.Due
+	}
+	rule.day = done.RecurDay
+	if rule.day == 0 {
+		rule.day = base.Day()
+	}
+	due := rule.after(base)
+	for due.Before(today()) {
+		due = rule.after(due)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	next := Task{
+		ID:          nextID(tasks),
+		Description: done.Description,
+		Status:      config.initialStatus(),
+		Priority:    done.Priority,
+		Due:         &due,
+		CreatedAt:   &now,
+		Parent:      done.Parent,
+		Recur:       done.Recur,
+	}
+	if rule.anchored() && due.Day() != rule.day {
+		next.RecurDay = rule.day
+	}
+	parseDescription(&next)
+	return next, nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+func TestRecurrenceAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		from time.Time
+		want time.Time
+	}{
+		{name: "daily", rule: "every day", from: date(2026, 12, 31), want: date(2027, 1, 1)},
+		{name: "every 2 weeks", rule: "every 2 weeks", from: date(2026, 10, 18), want: date(2026, 11, 1)},
+		{name: "weekday later in the week", rule: "every friday", from: date(2026, 10, 14), want: date(2026, 10, 16)},
+		{name: "weekday on the day", rule: "every wednesday", from: date(2026, 10, 14), want: date(2026, 10, 21)},
+		{name: "monthly", rule: "every month", from: date(2026, 10, 15), want: date(2026, 11, 15)},
+		{name: "monthly into a short month", rule: "every month", from: date(2026, 1, 31), want: date(2026, 2, 28)},
+		{name: "monthly into a leap february", rule: "every month", from: date(2028, 1, 31), want: date(2028, 2, 29)},
+		{name: "monthly over new year", rule: "1m", from: date(2026, 12, 31), want: date(2027, 1, 31)},
+		{name: "every 3 months", rule: "every 3 months", from: date(2026, 11, 30), want: date(2027, 2, 28)},
+		{name: "month day clamped", rule: "monthly on the 31st", from: date(2026, 4, 1), want: date(2026, 4, 30)},
+		{name: "month day next month", rule: "monthly on the 1st", from: date(2026, 10, 1), want: date(2026, 11, 1)},
+		{name: "yearly from a leap day", rule: "every year", from: date(2028, 2, 29), want: date(2029, 2, 28)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRecurrence(%q) error = %v", tt.rule, err)
+			}
+			if got := rule.after(tt.from); !got.Equal(tt.want) {
+				t.Errorf("after(%s) = %s, want %s", tt.from.Format(dateLayout), got.Format(dateLayout), tt.want.Format(dateLayout))
+			}
+		})
+	}
+}
+
+// nextLeapYear is the first leap year after this one. nextOccurrence skips
+// dates in the past, so the tests below are built around it.
+func nextLeapYear() int {
+	year := today().Year() + 1
+	for daysIn(year, time.February) != 29 {
+		year++
+	}
+	return year
+}
+
+// TestNextOccurrenceKeepsDay completes a task over and over and checks
+// that short months do not move later occurrences
+func TestNextOccurrenceKeepsDay(t *testing.T) {
+	leap := nextLeapYear()
+	tests := []struct {
+		name  string
+		rule  string
+		first time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "month end",
+			rule:  "every month",
+			first: date(leap+1, 1, 31),
+			want:  []time.Time{date(leap+1, 2, 28), date(leap+1, 3, 31), date(leap+1, 4, 30), date(leap+1, 5, 31)},
+		},
+		{
+			name:  "30th through february",
+			rule:  "every month",
+			first: date(leap-1, 12, 30),
+			want:  []time.Time{date(leap, 1, 30), date(leap, 2, 29), date(leap, 3, 30)},
+		},
+		{
+			name:  "every 2 months",
+			rule:  "every 2 months",
+			first: date(leap-1, 12, 31),
+			want:  []time.Time{date(leap, 2, 29), date(leap, 4, 30), date(leap, 6, 30), date(leap, 8, 31)},
+		},
+		{
+			name:  "leap day",
+			rule:  "every year",
+			first: date(leap, 2, 29),
+			want:  []time.Time{date(leap+1, 2, 28), date(leap+2, 2, 28), date(leap+3, 2, 28), date(leap+4, 2, 29)},
+		},
+		{
+			name:  "mid month",
+			rule:  "every month",
+			first: date(leap, 1, 15),
+			want:  []time.Time{date(leap, 2, 15), date(leap, 3, 15)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := tt.first
+			task := Task{ID: 1, Description: "Pay rent", Status: config.initialStatus(), Due: &due, Recur: tt.rule}
+			tasks := []Task{task}
+			for i, want := range tt.want {
+				next, err := nextOccurrence(tasks, task)
+				if err != nil {
+					t.Fatalf("nextOccurrence() error = %v", err)
+				}
+				if !next.Due.Equal(want) {
+					t.Fatalf("occurrence %d due %s, want %s", i+1, next.Due.Format(dateLayout), want.Format(dateLayout))
+				}
+				// the day survives a save through each format
+				for _, codec := range []taskCodec{jsonCodec{}, todoTxtCodec{}, icsCodec{}} {
+					decoded, encoded := roundTrip(t, codec, []Task{next})
+					if decoded[0].RecurDay != next.RecurDay {
+						t.Fatalf("RecurDay = %d after a round trip, want %d:\n%s", decoded[0].RecurDay, next.RecurDay, encoded)
+					}
+				}
+				tasks = append(tasks, next)
+				task = next
+			}
+		})
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	monday := time.Monday
+	tests := []struct {
+		rule    string
+		want    recurrence
+		wantErr bool
+	}{
+		{rule: "every day", want: recurrence{unit: "day", interval: 1}},
+		{rule: "Every  3 Weeks", want: recurrence{unit: "week", interval: 3}},
+		{rule: "every monday", want: recurrence{unit: "week", interval: 1, weekday: &monday}},
+		{rule: "monthly on the 1st", want: recurrence{unit: "month", interval: 1, monthDay: 1}},
+		{rule: "every month on the 31", want: recurrence{unit: "month", interval: 1, monthDay: 31}},
+		{rule: "2w", want: recurrence{unit: "week", interval: 2}},
+		{rule: "+1y", want: recurrence{unit: "year", interval: 1}},
+		{rule: "every 0 days", wantErr: true},
+		{rule: "0d", wantErr: true},
+		{rule: "monthly on the 32nd", wantErr: true},
+		{rule: "every someday", wantErr: true},
+		{rule: "fortnightly", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := parseRecurrence(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseRecurrence(%q) = %+v, want an error", tt.rule, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRecurrence(%q) error = %v", tt.rule, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRecurrence(%q) = %+v, want %+v", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceFirst(t *testing.T) {
+	tests := []struct {
+		rule string
+		from time.Time
+		want time.Time
+	}{
+		{rule: "every 2 days", from: date(2026, 10, 14), want: date(2026, 10, 14)},
+		{rule: "every wednesday", from: date(2026, 10, 14), want: date(2026, 10, 14)},
+		{rule: "every monday", from: date(2026, 10, 14), want: date(2026, 10, 19)},
+		{rule: "monthly on the 14th", from: date(2026, 10, 14), want: date(2026, 10, 14)},
+		{rule: "monthly on the 1st", from: date(2026, 10, 14), want: date(2026, 11, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.first(tt.from); !got.Equal(tt.want) {
+				t.Errorf("first(%s) = %s, want %s", tt.from.Format(dateLayout), got.Format(dateLayout), tt.want.Format(dateLayout))
+			}
+		})
+	}
+}
+
+func TestCompleteRecurringTask(t *testing.T) {
+	useTempTasksFile(t)
+	// long overdue: the next occurrence is the first one from today on
+	due := date(2020, 1, 6)
+	tasks := []Task{{ID: 1, Description: "Water plants +home", Priority: "B", Due: &due, Recur: "every monday", Parent: 3}}
+	tasks, err := completeTask(tasks, 1)
+	if err != nil {
+		t.Fatalf("completeTask() error = %v", err)
+	}
+	if len(tasks) != 2 || !tasks[0].isCompleted() {
+		t.Fatalf("completeTask() = %+v, want the task done and a new one", tasks)
+	}
+	next := tasks[1]
+	if next.ID != 2 || next.isCompleted() || next.Description != tasks[0].Description || next.Priority != "B" ||
+		next.Recur != "every monday" || next.Parent != 3 || len(next.Projects) != 1 {
+		t.Errorf("next occurrence = %+v", next)
+	}
+	if next.Due.Weekday() != time.Monday || next.Due.Before(today()) || !next.Due.Before(today().AddDate(0, 0, 7)) {
+		t.Errorf("next occurrence due %s, want the first monday from today", next.Due.Format(dateLayout))
+	}
+}
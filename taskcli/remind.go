@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Due dates are days, so for reminders a task is due at the configured
+// time of day on its due date. watch reminds of each task once, the lead
+// time before that.
+
+// parseLead parses a reminder lead time: a Go duration such as 90m or
+// 1h30m, or a number of days such as 1d
+func parseLead(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid lead time %q: use e.g. 30m, 2h or 1d", s)
+	}
+	return d, nil
+}
+
+// parseTimeOfDay parses HH:MM into the offset from midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: use HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// dueAt returns the moment an open task with a due date is due
+func (c Config) dueAt(task Task) time.Time {
+	offset, _ := parseTimeOfDay(c.DueTime)
+	return startOfDay(*task.Due).Add(offset)
+}
+
+// printAgenda prints overdue tasks and those due today or in the next
+// days. It prints nothing when nothing is due, to stay quiet in a shell
+// startup file.
+func printAgenda(tasks []Task, days int) {
+	until := today().AddDate(0, 0, days+1)
+	var overdue, upcoming []Task
+	for _, task := range tasks {
+		switch {
+		case task.isCompleted() || task.Due == nil:
+		case task.isOverdue():
+			overdue = append(overdue, task)
+		case task.Due.Before(until):
+			upcoming = append(upcoming, task)
+		}
+	}
+	sortTasks(overdue, "due")
+	sortTasks(upcoming, "due")
+
+	heading := "Today:"
+	if days > 0 {
+		heading = fmt.Sprintf("Today and the next %d day(s):", days)
+	}
+	printer := taskPrinter{all: tasks, color: useColor()}
+	printer.printSection("Overdue:", overdue, func(Task) bool { return true })
+	printer.printSection(heading, upcoming, func(Task) bool { return true })
+}
+
+// notifier shows reminders, on the desktop if notify-send is installed
+type notifier struct {
+	notifySend string // path of notify-send, empty to print instead
+}
+
+func newNotifier(stdout bool) notifier {
+	var n notifier
+	if !stdout {
+		n.notifySend, _ = exec.LookPath("notify-send")
+	}
+	return n
+}
+
+func (n notifier) notify(title, body string) {
+	if n.notifySend != "" {
+		if err := exec.Command(n.notifySend, "--app-name=taskcli", title, body).Run(); err == nil {
+			return
+		}
+	}
+	// the bell makes a terminal flag the window
+	fmt.Printf("\a[%s] %s: %s\n", time.Now().Format("15:04"), title, body)
+}
+
+// reminder describes when a task is due relative to now
+func reminder(task Task, due, now time.Time) (string, string) {
+	body := fmt.Sprintf("%d. %s", task.ID, task.Description)
+	if !due.After(now) {
+		return "Overdue", body
+	}
+	left := due.Sub(now).Round(time.Minute)
+	return "Due in " + formatDuration(left), body
+}
+
+// watcher remembers which tasks watch has already reminded of
+type watcher struct {
+	lead     time.Duration
+	n        notifier
+	reminded map[string]bool
+	started  bool
+}
+
+func newWatcher(lead time.Duration, n notifier) *watcher {
+	return &watcher{lead: lead, n: n, reminded: map[string]bool{}}
+}
+
+// check reminds of each open task whose reminder time has come and that
+// was not reminded of before. Tasks already overdue at the first check
+// are summed up in a single reminder.
+func (w *watcher) check(tasks []Task, now time.Time) {
+	key := func(task Task) string {
+		return fmt.Sprintf("%d@%s", task.ID, task.Due.Format(dateLayout))
+	}
+	var overdue []string
+	for _, task := range tasks {
+		if task.isCompleted() || task.Due == nil || w.reminded[key(task)] {
+			continue
+		}
+		due := config.dueAt(task)
+		if now.Before(due.Add(-w.lead)) {
+			continue
+		}
+		w.reminded[key(task)] = true
+		if !w.started && !due.After(now) {
+			overdue = append(overdue, fmt.Sprintf("%d. %s", task.ID, task.Description))
+			continue
+		}
+		w.n.notify(reminder(task, due, now))
+	}
+	if len(overdue) > 0 {
+		w.n.notify(fmt.Sprintf("%d overdue task(s)", len(overdue)), strings.Join(overdue, "\n"))
+	}
+	w.started = true
+}
+
+// watchTasks checks the tasks every interval. A file that cannot be read,
+// say while another program replaces it, is tried again on the next
+// check, and the error is printed once until a read succeeds.
+func watchTasks(lead, interval time.Duration, n notifier) {
+	w := newWatcher(lead, n)
+	var readErr string
+	for {
+		tasks, err := readTasks()
+		if err != nil {
+			if err.Error() != readErr {
+				fmt.Printf("[%s] Error reading tasks: %v; trying again\n", time.Now().Format("15:04"), err)
+				readErr = err.Error()
+			}
+			time.Sleep(interval)
+			continue
+		}
+		if readErr != "" {
+			fmt.Printf("[%s] Reading tasks again\n", time.Now().Format("15:04"))
+			readErr = ""
+		}
+		w.check(tasks, time.Now())
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLead(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{input: "30m", want: 30 * time.Minute},
+		{input: "1h30m", want: 90 * time.Minute},
+		{input: "2d", want: 48 * time.Hour},
+		{input: "0d", want: 0},
+	}
+	for _, tt := range tests {
+		if got, err := parseLead(tt.input); err != nil || got != tt.want {
+			t.Errorf("parseLead(%q) = %s, %v, want %s", tt.input, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "-1h", "-2d", "1w"} {
+		if _, err := parseLead(bad); err == nil {
+			t.Errorf("parseLead(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	if got, err := parseTimeOfDay("17:45"); err != nil || got != 17*time.Hour+45*time.Minute {
+		t.Errorf("parseTimeOfDay(17:45) = %s, %v", got, err)
+	}
+	for _, bad := range []string{"", "9am", "25:00", "12:60"} {
+		if _, err := parseTimeOfDay(bad); err == nil {
+			t.Errorf("parseTimeOfDay(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestPrintAgenda(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	day := func(n int) *time.Time {
+		d := today().AddDate(0, 0, n)
+		return &d
+	}
+	tasks := []Task{
+		{ID: 1, Description: "Late", Status: "todo", Due: day(-2)},
+		{ID: 2, Description: "Today", Status: "todo", Due: day(0)},
+		{ID: 3, Description: "Tomorrow", Status: "todo", Due: day(1)},
+		{ID: 4, Description: "Next week", Status: "todo", Due: day(7)},
+		{ID: 5, Description: "Done late", Status: "done", Due: day(-1)},
+		{ID: 6, Description: "Someday", Status: "todo"},
+	}
+
+	ids := func(out string) []string {
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			got = append(got, strings.Fields(line)[0])
+		}
+		return got
+	}
+	got := ids(captureStdout(t, func() { printAgenda(tasks, 0) }))
+	if want := "Overdue: 1. Today: 2."; strings.Join(got, " ") != want {
+		t.Errorf("printAgenda(0) printed %q, want %q", got, want)
+	}
+	got = ids(captureStdout(t, func() { printAgenda(tasks, 3) }))
+	if want := "Overdue: 1. Today 2. 3."; strings.Join(got, " ") != want {
+		t.Errorf("printAgenda(3) printed %q, want %q", got, want)
+	}
+	if out := captureStdout(t, func() { printAgenda(tasks[4:], 3) }); out != "" {
+		t.Errorf("printAgenda() with nothing due printed %q, want nothing", out)
+	}
+}
+
+func TestWatcherCheck(t *testing.T) {
+	// due dates are at 09:00 with the default config
+	tomorrow := today().AddDate(0, 0, 1)
+	yesterday := today().AddDate(0, 0, -1)
+	tasks := []Task{
+		{ID: 1, Description: "Late", Status: "todo", Due: &yesterday},
+		{ID: 2, Description: "Also late", Status: "todo", Due: &yesterday},
+		{ID: 3, Description: "Standup", Status: "todo", Due: &tomorrow},
+		{ID: 4, Description: "Done", Status: "done", Due: &yesterday},
+	}
+	w := newWatcher(time.Hour, notifier{})
+	check := func(now time.Time) string {
+		return captureStdout(t, func() { w.check(tasks, now) })
+	}
+
+	// the first check sums up what is already overdue
+	now := tomorrow.Add(7 * time.Hour)
+	if out := check(now); strings.Count(out, "\n") != 2 || !strings.Contains(out, "2 overdue task(s): 1. Late\n2. Also late") {
+		t.Errorf("first check printed %q, want one reminder of both overdue tasks", out)
+	}
+	// 08:15 is within the hour before 09:00
+	if out := check(now.Add(75 * time.Minute)); !strings.Contains(out, "Due in 0h45m: 3. Standup") {
+		t.Errorf("check in the lead time printed %q, want a reminder of 3", out)
+	}
+	// each task is reminded of once
+	if out := check(now.Add(3 * time.Hour)); out != "" {
+		t.Errorf("later check printed %q, want nothing", out)
+	}
+	// a new due date is a new reminder
+	later := tomorrow.AddDate(0, 0, 1)
+	tasks[2].Due = &later
+	if out := check(later.Add(10 * time.Hour)); !strings.Contains(out, "Overdue: 3. Standup") {
+		t.Errorf("check after moving the due date printed %q, want an overdue reminder", out)
+	}
+}
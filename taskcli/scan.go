@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// scan turns TODO, FIXME and XXX comments in a source tree into tasks
+// linked to their file and line. Scanning again moves the links along
+// with the comments, completes tasks whose comment is gone and reopens
+// them if it comes back.
+
+// a marker at the start of a comment's text, after repeats of the opener
+// such as /// or ## and the * of block comment lines, with an optional
+// (owner) and colon
+var markerPattern = regexp.MustCompile(`^[\s/#;!*-]*(TODO|FIXME|XXX)\b(?:\([^)]*\))?:?\s*(.*?)\s*$`)
+
+// commentSyntax is how a language writes comments, and the quotes of its
+// strings, inside which nothing is a comment
+type commentSyntax struct {
+	line   []string    // line comment openers
+	block  [][2]string // block comment openers and closers
+	quotes string
+}
+
+var (
+	cSyntax    = commentSyntax{line: []string{"//"}, block: [][2]string{{"/*", "*/"}}, quotes: "\"'`"}
+	hashSyntax = commentSyntax{line: []string{"#"}, quotes: `"'`}
+	dashSyntax = commentSyntax{line: []string{"--"}, block: [][2]string{{"/*", "*/"}}, quotes: `"'`}
+	lispSyntax = commentSyntax{line: []string{";"}, quotes: `"`}
+	markup     = commentSyntax{block: [][2]string{{"<!--", "-->"}}}
+)
+
+// commentSyntaxes maps file extensions to their language's comments.
+// Files with other extensions are not scanned.
+var commentSyntaxes = map[string]commentSyntax{
+	".go": cSyntax, ".c": cSyntax, ".h": cSyntax, ".cc": cSyntax, ".cpp": cSyntax, ".hpp": cSyntax,
+	".java": cSyntax, ".kt": cSyntax, ".scala": cSyntax, ".cs": cSyntax, ".swift": cSyntax, ".dart": cSyntax,
+	".js": cSyntax, ".jsx": cSyntax, ".ts": cSyntax, ".tsx": cSyntax, ".mjs": cSyntax, ".scss": cSyntax,
+	".proto": cSyntax, ".groovy": cSyntax,
+
+	".py": hashSyntax, ".rb": hashSyntax, ".sh": hashSyntax, ".bash": hashSyntax, ".zsh": hashSyntax,
+	".pl": hashSyntax, ".r": hashSyntax, ".yaml": hashSyntax, ".yml": hashSyntax, ".toml": hashSyntax,
+	".tf": hashSyntax, ".nix": hashSyntax, ".cmake": hashSyntax, ".mk": hashSyntax, ".ps1": hashSyntax,
+
+	".sql": dashSyntax, ".lua": dashSyntax,
+	".el": lispSyntax, ".lisp": lispSyntax, ".clj": lispSyntax, ".scm": lispSyntax,
+	".html": markup, ".htm": markup, ".xml": markup, ".svg": markup, ".md": markup, ".markdown": markup,
+
+	// ' starts lifetimes as well as characters
+	".rs":  {line: []string{"//"}, block: [][2]string{{"/*", "*/"}}, quotes: `"`},
+	".css": {block: [][2]string{{"/*", "*/"}}, quotes: `"'`},
+	".php": {line: []string{"//", "#"}, block: [][2]string{{"/*", "*/"}}, quotes: `"'`},
+	".ini": {line: []string{";", "#"}, quotes: `"`},
+	".hs":  {line: []string{"--"}, block: [][2]string{{"{-", "-}"}}, quotes: `"`},
+	".vue": {line: []string{"//"}, block: [][2]string{{"/*", "*/"}, {"<!--", "-->"}}, quotes: "\"'`"},
+}
+
+// files known by name rather than extension
+var commentSyntaxesByName = map[string]commentSyntax{
+	"Makefile": hashSyntax, "makefile": hashSyntax, "GNUmakefile": hashSyntax,
+	"Dockerfile": hashSyntax, "Containerfile": hashSyntax, "Rakefile": hashSyntax, "Gemfile": hashSyntax,
+}
+
+func commentSyntaxFor(name string) (commentSyntax, bool) {
+	if syntax, ok := commentSyntaxesByName[name]; ok {
+		return syntax, true
+	}
+	syntax, ok := commentSyntaxes[strings.ToLower(filepath.Ext(name))]
+	return syntax, ok
+}
+
+// commentScanner finds the comments of a file line by line, carrying a
+// block comment that is still open over to the next line
+type commentScanner struct {
+	syntax commentSyntax
+	closer string // closer of the open block comment
+}
+
+// comments returns the text of each comment on a line. A string ends at
+// its closing quote or the end of the line.
+func (s *commentScanner) comments(line string) []string {
+	var texts []string
+	i := 0
+	for {
+		if s.closer != "" {
+			end := strings.Index(line[i:], s.closer)
+			if end < 0 {
+				return append(texts, line[i:])
+			}
+			texts = append(texts, line[i:i+end])
+			i += end + len(s.closer)
+			s.closer = ""
+		}
+		if i >= len(line) {
+			return texts
+		}
+		if quote := line[i]; strings.IndexByte(s.syntax.quotes, quote) >= 0 {
+			for i++; i < len(line) && line[i] != quote; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			i++
+			continue
+		}
+		rest := line[i:]
+		for _, opener := range s.syntax.line {
+			if strings.HasPrefix(rest, opener) {
+				return append(texts, rest[len(opener):])
+			}
+		}
+		i++
+		for _, block := range s.syntax.block {
+			if strings.HasPrefix(rest, block[0]) {
+				s.closer = block[1]
+				i += len(block[0]) - 1
+				break
+			}
+		}
+	}
+}
+
+// directories that hold dependencies or build output rather than code
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true, "target": true, "dist": true, "build": true}
+
+// files that mark the root of a project
+var projectMarkers = []string{"go.mod", "package.json", "Cargo.toml", "pyproject.toml", "setup.py", ".git"}
+
+const maxScanFileSize = 1 << 20
+
+// todoComment is one marker comment found by a scan
+type todoComment struct {
+	file    string // absolute path
+	line    int
+	kind    string // TODO, FIXME or XXX
+	text    string
+	project string
+}
+
+// projectFor names the project a file belongs to: the nearest directory
+// with a project marker, up to the scanned root
+func projectFor(dir, root string, cache map[string]string) string {
+	if project, ok := cache[dir]; ok {
+		return project
+	}
+	project := filepath.Base(root)
+	for _, marker := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			project = filepath.Base(dir)
+			cache[dir] = project
+			return project
+		}
+	}
+	if dir != root && strings.HasPrefix(dir, root) {
+		project = projectFor(filepath.Dir(dir), root, cache)
+	}
+	cache[dir] = project
+	return project
+}
+
+// scanComments walks root for marker comments, skipping hidden and
+// dependency directories and files that look binary or are very large.
+// Directories and files that cannot be read are reported and skipped.
+func scanComments(root string) ([]todoComment, error) {
+	var comments []todoComment
+	projects := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", path, err)
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || skippedDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		syntax, ok := commentSyntaxFor(name)
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxScanFileSize || strings.HasPrefix(name, ".") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", path, err)
+			return nil
+		}
+		if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			return nil
+		}
+
+		comments = append(comments, fileComments(data, syntax, path, projectFor(filepath.Dir(path), root, projects))...)
+		return nil
+	})
+	return comments, err
+}
+
+// fileComments finds the marker comments in a file's contents
+func fileComments(data []byte, syntax commentSyntax, path, project string) []todoComment {
+	var comments []todoComment
+	lines := &commentScanner{syntax: syntax}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, maxScanFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		for _, text := range lines.comments(scanner.Text()) {
+			m := markerPattern.FindStringSubmatch(text)
+			if m == nil || m[2] == "" {
+				continue
+			}
+			comments = append(comments, todoComment{file: path, line: line, kind: m[1], text: m[2], project: project})
+			break
+		}
+	}
+	return comments
+}
+
+// scanResult counts what a scan did
+type scanResult struct {
+	found, added, moved, closed, reopened int
+}
+
+// scanTree creates tasks for new marker comments under dir, updates the
+// line of moved ones and completes open tasks whose comment disappeared.
+// A task it completed that way is reopened when its comment is back; one
+// completed by hand stays done.
+// A project name, if given, replaces the one found from project markers.
+func scanTree(tasks []Task, dir, project string) ([]Task, scanResult, error) {
+	var result scanResult
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, result, err
+	}
+	comments, err := scanComments(root)
+	if err != nil {
+		return nil, result, err
+	}
+	result.found = len(comments)
+
+	// comments are matched to tasks by file and text, nearest line first,
+	// so edits elsewhere in the file do not create new tasks
+	var linked []int
+	for i, task := range tasks {
+		src := task.Source
+		if src != nil && src.Kind == sourceComment && strings.HasPrefix(src.File, root+string(filepath.Separator)) {
+			linked = append(linked, i)
+		}
+	}
+	matched := map[int]bool{}
+	var fresh []todoComment
+	for _, c := range comments {
+		best := -1
+		for _, i := range linked {
+			src := tasks[i].Source
+			if matched[i] || src.File != c.file || src.Text != c.text {
+				continue
+			}
+			if best < 0 || abs(src.Line-c.line) < abs(tasks[best].Source.Line-c.line) {
+				best = i
+			}
+		}
+		if best < 0 {
+			fresh = append(fresh, c)
+			continue
+		}
+		matched[best] = true
+		if tasks[best].Source.Line != c.line {
+			tasks[best].Source.Line = c.line
+			result.moved++
+		}
+		if tasks[best].Source.Gone {
+			// unless it was reopened by hand already
+			if tasks[best].isCompleted() {
+				if tasks, err = reopenTask(tasks, tasks[best].ID); err != nil {
+					return nil, result, err
+				}
+				result.reopened++
+			}
+			setGone(&tasks[best], false)
+		}
+	}
+
+	for _, i := range linked {
+		if matched[i] || tasks[i].isCompleted() {
+			continue
+		}
+		if tasks, err = completeTask(tasks, tasks[i].ID); err != nil {
+			return nil, result, err
+		}
+		setGone(&tasks[i], true)
+		result.closed++
+	}
+
+	for _, c := range fresh {
+		name := c.project
+		if project != "" {
+			name = project
+		}
+		description := c.text + " +" + strings.ReplaceAll(name, " ", "-") + " #" + strings.ToLower(c.kind)
+		tasks = addTask(tasks, description)
+		tasks[len(tasks)-1].Source = &taskSource{Kind: sourceComment, File: c.file, Line: c.line, Text: c.text}
+		result.added++
+	}
+	return tasks, result, nil
+}
+
+// setGone records on a task's own copy of its source whether scan
+// completed it because its comment was removed
+func setGone(task *Task, gone bool) {
+	src := *task.Source
+	src.Gone = gone
+	task.Source = &src
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileComments(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		want []string // line kind: text
+	}{
+		{
+			name: "go",
+			file: "main.go",
+			data: "package main\n" +
+				"// TODO: handle timeouts\n" +
+				"x := 1 // FIXME(ana): off by one\n" +
+				"/// XXX doc comment\n" +
+				"/* TODO block on one line */\n" +
+				"/*\n" +
+				" * TODO: inside a block\n" +
+				" */\n" +
+				"//TODO\n",
+			want: []string{"2 TODO: handle timeouts", "3 FIXME: off by one", "4 XXX: doc comment", "5 TODO: block on one line", "7 TODO: inside a block"},
+		},
+		{
+			name: "go strings are not comments",
+			file: "main.go",
+			data: `fmt.Println("// TODO: not a comment")` + "\n" +
+				`s := "TODO: in a string" // TODO: after the string` + "\n" +
+				`r := ` + "`# TODO raw`" + "\n" +
+				`q := "escaped \" // TODO: still a string"` + "\n" +
+				`url := "http://example.com" // TODO real one` + "\n" +
+				"c := '/' // XXX rune\n",
+			want: []string{"2 TODO: after the string", "5 TODO: real one", "6 XXX: rune"},
+		},
+		{
+			name: "markdown headings and lists are not comments",
+			file: "README.md",
+			data: "# TODO list\n" +
+				"## TODO: things\n" +
+				"- TODO buy milk\n" +
+				"<!-- TODO: fix this section -->\n" +
+				"<!--\n" +
+				"FIXME: rewrite\n" +
+				"-->\n" +
+				"text // TODO not a comment\n",
+			want: []string{"4 TODO: fix this section", "6 FIXME: rewrite"},
+		},
+		{
+			name: "python",
+			file: "tool.py",
+			data: "# TODO: first\n" +
+				"## FIXME double hash\n" +
+				"x = '# TODO in a string'\n" +
+				"y = \"// TODO\"  # XXX after the string\n" +
+				"// TODO not python\n" +
+				"def f():\n" +
+				"    \"\"\"TODO docstring\"\"\"\n",
+			want: []string{"1 TODO: first", "2 FIXME: double hash", "4 XXX: after the string"},
+		},
+		{
+			name: "sql",
+			file: "schema.sql",
+			data: "-- TODO: index\n" +
+				"SELECT '-- TODO in a string';\n" +
+				"/* FIXME: slow */\n" +
+				"# TODO not sql\n",
+			want: []string{"1 TODO: index", "3 FIXME: slow"},
+		},
+		{
+			name: "lisp",
+			file: "init.el",
+			data: ";; TODO: bind keys\n(message \"; TODO no\")\n",
+			want: []string{"1 TODO: bind keys"},
+		},
+		{
+			name: "rust lifetimes",
+			file: "lib.rs",
+			data: "fn f<'a>(s: &'a str) {} // TODO: borrow less\n",
+			want: []string{"1 TODO: borrow less"},
+		},
+		{
+			name: "makefile by name",
+			file: "Makefile",
+			data: "# TODO: parallel builds\n",
+			want: []string{"1 TODO: parallel builds"},
+		},
+		{
+			name: "marker must start the comment",
+			file: "main.go",
+			data: "// see the TODO in main\n// TODOS are fine\n// XXXL size\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syntax, ok := commentSyntaxFor(tt.file)
+			if !ok {
+				t.Fatalf("no comment syntax for %s", tt.file)
+			}
+			var got []string
+			for _, c := range fileComments([]byte(tt.data), syntax, tt.file, "p") {
+				got = append(got, fmt.Sprintf("%d %s: %s", c.line, c.kind, c.text))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fileComments() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTree creates files under root, making directories as needed
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanComments(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"go.mod":              "module example.com/app\n",
+		"main.go":             "package main\n\n// TODO: flags\n",
+		"NOTES.md":            "# TODO\n## TODO list\n",
+		"notes.txt":           "# TODO: plain text has no comments\n",
+		"data.bin":            "\x00// TODO: binary\n",
+		"tools/gen/gen.py":    "# FIXME: slow\n",
+		"tools/gen/go.mod":    "module example.com/gen\n",
+		"vendor/lib/lib.go":   "// TODO: skipped\n",
+		".hidden/x.go":        "// TODO: skipped\n",
+		"web/index.html":      "<p>TODO</p>\n<!-- XXX: a11y -->\n",
+		"web/app.js":          "const s = '// TODO: nope';\n",
+		"web/build/bundle.js": "// TODO: skipped\n",
+	})
+
+	comments, err := scanComments(root)
+	if err != nil {
+		t.Fatalf("scanComments() error = %v", err)
+	}
+	var got []string
+	for _, c := range comments {
+		rel, _ := filepath.Rel(root, c.file)
+		got = append(got, fmt.Sprintf("%s:%d %s %s +%s", filepath.ToSlash(rel), c.line, c.kind, c.text, c.project))
+	}
+	app := filepath.Base(root)
+	want := []string{
+		"main.go:3 TODO flags +" + app,
+		"tools/gen/gen.py:1 FIXME slow +gen",
+		"web/index.html:2 XXX a11y +" + app,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanComments() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestScanTree(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go": "package main\n\n// TODO: flags\n// FIXME: leaks\n",
+	})
+
+	tasks, result, err := scanTree(nil, root, "app")
+	if err != nil {
+		t.Fatalf("scanTree() error = %v", err)
+	}
+	if result != (scanResult{found: 2, added: 2}) || len(tasks) != 2 {
+		t.Fatalf("first scan = %+v, %+v, want 2 tasks added", result, tasks)
+	}
+	if tasks[0].Description != "flags +app #todo" || tasks[1].Source.Line != 4 {
+		t.Errorf("first scan tasks = %+v", tasks)
+	}
+
+	// a line added above moves both comments, and the fixed one is gone
+	writeTree(t, root, map[string]string{
+		"main.go": "package main\n\nimport \"os\"\n\n// TODO: flags\n",
+	})
+	tasks, result, err = scanTree(tasks, root, "app")
+	if err != nil {
+		t.Fatalf("scanTree() error = %v", err)
+	}
+	if result != (scanResult{found: 1, moved: 1, closed: 1}) || len(tasks) != 2 {
+		t.Errorf("second scan = %+v, want 1 moved and 1 closed", result)
+	}
+	if tasks[0].Source.Line != 5 || tasks[0].isCompleted() || !tasks[1].isCompleted() {
+		t.Errorf("second scan tasks = %+v", tasks)
+	}
+
+	// the comment is back, so the task scan closed is reopened, but one
+	// completed by hand stays done
+	if tasks, err = completeTask(tasks, tasks[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, root, map[string]string{
+		"main.go": "package main\n\nimport \"os\"\n\n// TODO: flags\n// FIXME: leaks\n",
+	})
+	tasks, result, err = scanTree(tasks, root, "app")
+	if err != nil {
+		t.Fatalf("scanTree() error = %v", err)
+	}
+	if result != (scanResult{found: 2, moved: 1, reopened: 1}) || len(tasks) != 2 {
+		t.Errorf("third scan = %+v, want 1 moved and 1 reopened", result)
+	}
+	if !tasks[0].isCompleted() || tasks[1].isCompleted() || tasks[1].Source.Gone || tasks[1].Source.Line != 6 {
+		t.Errorf("third scan tasks = %+v", tasks)
+	}
+}
+
+func TestScanCommentsSkipsUnreadableDirs(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":        "// TODO: flags\n",
+		"secret/main.go": "// TODO: hidden\n",
+	})
+	secret := filepath.Join(root, "secret")
+	if err := os.Chmod(secret, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(secret, 0755) })
+
+	comments, err := scanComments(root)
+	if err != nil {
+		t.Fatalf("scanComments() error = %v, want the directory skipped", err)
+	}
+	if len(comments) != 1 || comments[0].text != "flags" {
+		t.Errorf("scanComments() = %+v, want the readable comment", comments)
+	}
+}
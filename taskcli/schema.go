@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The JSON tasks file is an envelope holding the version of its schema:
+//
+//	{"version": 2, "next_id": 12, "tasks": [...]}
+//
+// next_id is one more than the highest ID ever handed out, removed and
+// archived tasks included. Version 1 is the bare array written before the
+// envelope existed. Older versions are migrated when the file is read, one
+// version at a time, and the file is written in the current version on the
+// next save.
+
+const schemaVersion = 2
+
+// tasksDocument is the JSON tasks file from version 2 on
+type tasksDocument struct {
+	Version int             `json:"version"`
+	NextID  int             `json:"next_id,omitempty"` // missing in files written before it was added
+	Tasks   json.RawMessage `json:"tasks"`
+}
+
+// rawTask is a task as stored. Migrations work on these rather than on
+// Task, so they keep working after Task changes.
+type rawTask = map[string]any
+
+// migrations[v] upgrades the tasks of a version v file to version v+1
+var migrations = map[int]func([]rawTask) ([]rawTask, error){
+	1: migrateV1,
+}
+
+// migrateV1 turns the completed flag that predates statuses into a status
+func migrateV1(tasks []rawTask) ([]rawTask, error) {
+	for _, task := range tasks {
+		completed, _ := task["completed"].(bool)
+		delete(task, "completed")
+		status, _ := task["status"].(string)
+		switch {
+		case completed:
+			task["status"] = config.doneStatus()
+		case status == "":
+			task["status"] = config.initialStatus()
+		}
+	}
+	return tasks, nil
+}
+
+// fileSchemaVersion tells the schema version of a JSON tasks file
+func fileSchemaVersion(data []byte) (int, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return schemaVersion, nil
+	}
+	if data[0] == '[' {
+		return 1, nil
+	}
+	var doc tasksDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	if doc.Version < 2 {
+		return 0, errors.New("tasks file has no valid version")
+	}
+	return doc.Version, nil
+}
+
+// decodeTasksDocument reads a JSON tasks file of any version up to the
+// current one. It returns the file's next_id too, 0 if it has none; only
+// the tasks file itself sets where new IDs start, not imported or archived
+// files.
+func decodeTasksDocument(data []byte) ([]Task, int, error) {
+	version, err := fileSchemaVersion(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > schemaVersion {
+		return nil, 0, fmt.Errorf("tasks file is version %d, but this taskcli only reads up to version %d: upgrade taskcli", version, schemaVersion)
+	}
+	// an empty file is an empty list rather than a parse error
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return []Task{}, 0, nil
+	}
+	next := 0
+	if version > 1 {
+		var doc tasksDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, 0, err
+		}
+		next = doc.NextID
+		if len(doc.Tasks) == 0 {
+			return []Task{}, next, nil
+		}
+		data = doc.Tasks
+	}
+
+	if version < schemaVersion {
+		migrated, err := migrateTasks(data, version)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = migrated
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, 0, err
+	}
+	if tasks == nil {
+		tasks = []Task{}
+	}
+	return tasks, next, nil
+}
+
+// migrateTasks runs the migrations from version on the raw task list
+func migrateTasks(data []byte, version int) ([]byte, error) {
+	var tasks []rawTask
+	// numbers stay as written rather than going through float64
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tasks); err != nil {
+		return nil, err
+	}
+	for v := version; v < schemaVersion; v++ {
+		migrate, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration from version %d", v)
+		}
+		var err error
+		if tasks, err = migrate(tasks); err != nil {
+			return nil, fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+	}
+	return json.Marshal(tasks)
+}
+
+// encodeTasksDocument writes tasks in the current version
+func encodeTasksDocument(tasks []Task) ([]byte, error) {
+	if tasks == nil {
+		tasks = []Task{}
+	}
+	doc := struct {
+		Version int    `json:"version"`
+		NextID  int    `json:"next_id"`
+		Tasks   []Task `json:"tasks"`
+	}{schemaVersion, nextIDToSave(tasks), tasks}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// backupBeforeMigrating copies a JSON tasks file of an older version to
+// <file>.v<version>.bak, unless there is such a backup already, so going
+// back to an older taskcli stays possible
+func backupBeforeMigrating(path string) error {
+	if formatForPath(path) != "json" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// a file that cannot be read is reported when it is decoded
+	version, err := fileSchemaVersion(data)
+	if err != nil || version >= schemaVersion {
+		return nil
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if _, err := os.Stat(backup); err == nil {
+		return nil
+	}
+	if err := writeFileAtomic(backup, data); err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+	fmt.Fprintf(os.Stderr, "Migrating %s from version %d to %d; the old file is saved as %s\n", path, version, schemaVersion, backup)
+	return nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestMigrationGolden decodes each file in testdata/migrate and compares
+// what it is written back as with the .golden file next to it
+func TestMigrationGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "migrate", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no migration test files found")
+	}
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// loaded as the tasks file, so its next_id is kept
+			tasks, next, err := decodeTasksDocument(data)
+			if err != nil {
+				t.Fatalf("decodeTasksDocument() error = %v", err)
+			}
+			highestID = max(0, next-1)
+			var got bytes.Buffer
+			if err := (jsonCodec{}).encode(&got, tasks); err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+			got.WriteString("\n")
+
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("reading golden file: %v (run go test -update to create it)", err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("migrated %s =\n%s\nwant\n%s", input, got.String(), want)
+			}
+
+			// the migrated file is read back unchanged
+			again, _, err := decodeTasksDocument(got.Bytes())
+			if err != nil {
+				t.Fatalf("decoding the migrated file: %v", err)
+			}
+			data, _ = encodeTasksDocument(again)
+			if string(data)+"\n" != got.String() {
+				t.Errorf("migrated file does not round-trip:\n%s", data)
+			}
+		})
+	}
+}
+
+// TestIDsAreNotReused removes and archives the newest task and checks that
+// the next run numbers new tasks after it all the same
+func TestIDsAreNotReused(t *testing.T) {
+	// todo.txt has no room for next_id, so it is kept in a file next to it
+	for _, name := range []string{"tasks.json", "todo.txt"} {
+		t.Run(name, func(t *testing.T) {
+			useTempTasksFile(t)
+			tasksFile = filepath.Join(t.TempDir(), name)
+			checkIDsAreNotReused(t)
+		})
+	}
+}
+
+func checkIDsAreNotReused(t *testing.T) {
+	t.Helper()
+	tasks := addTask(addTask(addTask([]Task{}, "a"), "b"), "c")
+	if err := saveTasks(tasks); err != nil {
+		t.Fatalf("saveTasks() error = %v", err)
+	}
+
+	steps := []struct {
+		name   string
+		change func([]Task) ([]Task, error)
+		wantID int
+	}{
+		{name: "removed", change: func(tasks []Task) ([]Task, error) { return removeTasks(tasks, []int{3}), nil }, wantID: 4},
+		{name: "archived", change: func(tasks []Task) ([]Task, error) { return archiveTasks(tasks, []int{4}) }, wantID: 5},
+		{name: "both", change: func(tasks []Task) ([]Task, error) {
+			archived, err := archiveTasks(tasks, []int{5})
+			return removeTasks(archived, []int{2}), err
+		}, wantID: 6},
+	}
+	for _, step := range steps {
+		// a new run knows only what the file holds
+		highestID = 0
+		tasks, err := loadTasks()
+		if err != nil {
+			t.Fatalf("%s: loadTasks() error = %v", step.name, err)
+		}
+		if tasks, err = step.change(tasks); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		if err := saveTasks(tasks); err != nil {
+			t.Fatalf("%s: saveTasks() error = %v", step.name, err)
+		}
+
+		highestID = 0
+		if tasks, err = loadTasks(); err != nil {
+			t.Fatalf("%s: loadTasks() error = %v", step.name, err)
+		}
+		tasks = addTask(tasks, "new")
+		if got := tasks[len(tasks)-1].ID; got != step.wantID {
+			t.Errorf("%s: new task got ID %d, want %d", step.name, got, step.wantID)
+		}
+		if err := saveTasks(tasks); err != nil {
+			t.Fatalf("%s: saveTasks() error = %v", step.name, err)
+		}
+	}
+}
+
+func TestOnlyTheTasksFileSetsNextID(t *testing.T) {
+	useTempTasksFile(t)
+	other := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(other, []byte(`{"version": 2, "next_id": 100, "tasks": [{"id": 4, "description": "a"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// an imported or archived file does not move where new IDs start
+	if _, err := readTasksFile(other, "json"); err != nil {
+		t.Fatalf("readTasksFile() error = %v", err)
+	}
+	if highestID != 0 {
+		t.Errorf("highestID = %d after reading another file, want 0", highestID)
+	}
+
+	if err := os.Rename(other, tasksFile); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := loadTasks()
+	if err != nil {
+		t.Fatalf("loadTasks() error = %v", err)
+	}
+	if got := nextID(tasks); got != 100 {
+		t.Errorf("nextID() after loading the tasks file = %d, want 100", got)
+	}
+}
+
+func TestMigrationsCoverEveryVersion(t *testing.T) {
+	for v := 1; v < schemaVersion; v++ {
+		if migrations[v] == nil {
+			t.Errorf("no migration from version %d to %d", v, v+1)
+		}
+	}
+}
+
+func TestDecodeRejectsUnknownVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "newer version",
+			data:    `{"version": 99, "tasks": []}`,
+			wantErr: "upgrade taskcli",
+		},
+		{
+			name:    "missing version",
+			data:    `{"tasks": []}`,
+			wantErr: "no valid version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeTasksDocument([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decodeTasksDocument() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupBeforeMigrating(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	old, err := os.ReadFile(filepath.Join("testdata", "migrate", "v1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, old, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backupBeforeMigrating(path); err != nil {
+		t.Fatalf("backupBeforeMigrating() error = %v", err)
+	}
+	backup := path + ".v1.bak"
+	got, err := os.ReadFile(backup)
+	if err != nil {
+		t.Fatalf("no backup was made: %v", err)
+	}
+	if !bytes.Equal(got, old) {
+		t.Errorf("backup differs from the original file")
+	}
+
+	// a later run leaves the first backup alone
+	if err := os.WriteFile(path, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := backupBeforeMigrating(path); err != nil {
+		t.Fatalf("backupBeforeMigrating() error = %v", err)
+	}
+	if got, _ := os.ReadFile(backup); !bytes.Equal(got, old) {
+		t.Errorf("backup was overwritten")
+	}
+
+	// files in the current version need no backup
+	current := filepath.Join(dir, "current.json")
+	if err := os.WriteFile(current, []byte(`{"version": 2, "tasks": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := backupBeforeMigrating(current); err != nil {
+		t.Fatalf("backupBeforeMigrating() error = %v", err)
+	}
+	if backups, _ := filepath.Glob(current + ".*.bak"); len(backups) > 0 {
+		t.Errorf("backed up a file that needs no migration: %v", backups)
+	}
+}
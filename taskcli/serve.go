@@ -0,0 +1,405 @@
+// The routes below use method and wildcard patterns, which need the
+// ServeMux of Go 1.22 and later even when built without a go.mod.
+//go:debug httpmuxgo121=0
+
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// serve exposes the tasks over a JSON API with a small dashboard on top.
+// Like the ui action it holds the lock only while handling a request, and
+// every change is one updateTasks call, journaled like the matching
+// command, so the CLI can keep working on the same file.
+
+//go:embed web
+var webFiles embed.FS
+
+// apiError is a request that cannot be served, with its HTTP status
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+// taskRequest is the body of create and update requests. Fields left out
+// of an update keep their value; an empty priority or due date clears it.
+type taskRequest struct {
+	Description *string `json:"description"`
+	Priority    *string `json:"priority"` // A-D or high/medium/low
+	Due         *string `json:"due"`      // anything --due accepts
+	Parent      *int    `json:"parent"`
+	Recur       *string `json:"recur"`
+	Status      *string `json:"status"`
+}
+
+func runServer(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tasks", handleListTasks)
+	mux.HandleFunc("POST /api/tasks", handleCreateTask)
+	mux.HandleFunc("GET /api/tasks/{id}", handleGetTask)
+	mux.HandleFunc("PATCH /api/tasks/{id}", handleUpdateTask)
+	mux.HandleFunc("DELETE /api/tasks/{id}", handleDeleteTask)
+	mux.HandleFunc("POST /api/tasks/{id}/done", handleCompleteTask)
+	mux.HandleFunc("POST /api/tasks/{id}/undone", handleReopenTask)
+	mux.HandleFunc("GET /api/statuses", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, config.Statuses)
+	})
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		return err
+	}
+	mux.Handle("GET /", http.FileServerFS(static))
+
+	fmt.Printf("Serving %s on http://%s\n", tasksFile, addr)
+	return http.ListenAndServe(addr, localHost(addr, sameOrigin(mux)))
+}
+
+// localHost refuses requests for any host but the one the server listens
+// on or localhost. A page whose own domain was made to resolve to this
+// machine (DNS rebinding) passes the origin check, but still sends its
+// domain as the Host.
+func localHost(addr string, next http.Handler) http.Handler {
+	listenHost, _, _ := net.SplitHostPort(addr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r.Host, listenHost) {
+			writeError(w, &apiError{http.StatusForbidden, fmt.Sprintf("unknown host %q: use http://%s", r.Host, addr)})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether a request's Host names this machine. A
+// server listening on every interface is reached by any of its addresses,
+// which are IP addresses rather than names that could be rebound.
+func allowedHost(host, listenHost string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") || strings.EqualFold(host, listenHost) {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	listenIP := net.ParseIP(listenHost)
+	return listenHost == "" || (listenIP != nil && listenIP.IsUnspecified())
+}
+
+// sameOrigin refuses changes requested by pages from other sites, which
+// browsers mark with an Origin header
+func sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if r.Method != http.MethodGet && origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeError(w, &apiError{http.StatusForbidden, "cross-origin requests are not allowed"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with the error as JSON. Errors that are not an
+// apiError come from reading or writing the store.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		status = apiErr.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// conflict turns an error from a task operation into a 409, or a 404 if
+// the task does not exist
+func conflict(tasks []Task, id int, err error) error {
+	if findTask(tasks, id) < 0 {
+		return &apiError{http.StatusNotFound, fmt.Sprintf("no task with ID %d", id)}
+	}
+	return &apiError{http.StatusConflict, err.Error()}
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := parseID(r.PathValue("id"))
+	if err != nil {
+		return 0, &apiError{http.StatusBadRequest, err.Error()}
+	}
+	return id, nil
+}
+
+// readTaskRequest decodes a JSON body
+func readTaskRequest(r *http.Request) (taskRequest, error) {
+	var req taskRequest
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return req, &apiError{http.StatusUnsupportedMediaType, "requests must be application/json"}
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return req, &apiError{http.StatusBadRequest, "invalid request body: " + err.Error()}
+	}
+	return req, nil
+}
+
+// operationFor describes a request in the journal
+func operationFor(r *http.Request) string {
+	return "serve: " + r.Method + " " + r.URL.Path
+}
+
+func handleListTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := readTasks()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sortBy := r.URL.Query().Get("sort")
+	switch sortBy {
+	case "":
+		sortBy = "id"
+	case "id", "due", "priority", "created":
+	default:
+		writeError(w, &apiError{http.StatusBadRequest, fmt.Sprintf("invalid sort %q: use id, due, priority or created", sortBy)})
+		return
+	}
+	shown := []Task{}
+	var filter taskFilter
+	if expr := r.URL.Query().Get("filter"); strings.TrimSpace(expr) != "" {
+		if filter, err = parseFilter(expr, config); err != nil {
+			writeError(w, &apiError{http.StatusBadRequest, "invalid filter: " + err.Error()})
+			return
+		}
+	}
+	for _, task := range tasks {
+		if filter == nil || filter(task) {
+			shown = append(shown, task)
+		}
+	}
+	sortTasks(shown, sortBy)
+	writeJSON(w, http.StatusOK, shown)
+}
+
+func handleGetTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	tasks, err := readTasks()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	i := findTask(tasks, id)
+	if i < 0 {
+		writeError(w, &apiError{http.StatusNotFound, fmt.Sprintf("no task with ID %d", id)})
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks[i])
+}
+
+// applyTaskRequest sets the fields given in a request on a task. force
+// lets a status change complete a task with open subtasks or blockers.
+func applyTaskRequest(tasks []Task, i int, req taskRequest, force bool) ([]Task, error) {
+	invalid := func(err error) error { return &apiError{http.StatusBadRequest, err.Error()} }
+	task := &tasks[i]
+	if req.Description != nil {
+		if strings.TrimSpace(*req.Description) == "" {
+			return nil, &apiError{http.StatusBadRequest, "description must not be empty"}
+		}
+		task.Description = *req.Description
+		parseDescription(task)
+	}
+	if req.Priority != nil {
+		task.Priority = ""
+		if *req.Priority != "" {
+			p, err := parsePriority(*req.Priority)
+			if err != nil {
+				return nil, invalid(err)
+			}
+			task.Priority = p
+		}
+	}
+	if req.Due != nil {
+		task.Due, task.RecurDay = nil, 0
+		if *req.Due != "" {
+			due, err := parseDate(*req.Due)
+			if err != nil {
+				return nil, invalid(err)
+			}
+			task.Due = &due
+		}
+	}
+	if req.Parent != nil {
+		if _, err := setParent(tasks, task.ID, *req.Parent); err != nil {
+			return nil, invalid(fmt.Errorf("invalid parent: %w", err))
+		}
+	}
+	if req.Recur != nil {
+		task.Recur, task.RecurDay = "", 0
+		if *req.Recur != "" {
+			rule, err := parseRecurrence(*req.Recur)
+			if err != nil {
+				return nil, invalid(err)
+			}
+			task.Recur = *req.Recur
+			if task.Due == nil {
+				first := rule.first(today())
+				task.Due = &first
+			}
+		}
+	}
+	if req.Status != nil && *req.Status != task.Status {
+		moved, err := moveTask(tasks, task.ID, *req.Status, force)
+		var open *openDependenciesError
+		if errors.As(err, &open) {
+			return nil, &apiError{http.StatusConflict, err.Error() + "; add ?force=true to complete it anyway"}
+		}
+		if err != nil {
+			return nil, invalid(err)
+		}
+		tasks = moved
+	}
+	return tasks, nil
+}
+
+func handleCreateTask(w http.ResponseWriter, r *http.Request) {
+	req, err := readTaskRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Description == nil {
+		writeError(w, &apiError{http.StatusBadRequest, "description is required"})
+		return
+	}
+	var created Task
+	_, err = updateTasks(operationFor(r), func(tasks []Task) ([]Task, error) {
+		// the new ID is only taken once the rest of the request is valid,
+		// so a rejected request does not use it up
+		reserved := highestID
+		tasks = addTask(tasks, *req.Description)
+		id := tasks[len(tasks)-1].ID
+		tasks, err := applyTaskRequest(tasks, len(tasks)-1, req, false)
+		if err != nil {
+			highestID = reserved
+			return nil, err
+		}
+		created = tasks[findTask(tasks, id)]
+		return tasks, nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// changeTask runs a change to one task and answers with the task after it
+func changeTask(w http.ResponseWriter, r *http.Request, change func(tasks []Task, id int) ([]Task, error)) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var changed Task
+	_, err = updateTasks(operationFor(r), func(tasks []Task) ([]Task, error) {
+		if findTask(tasks, id) < 0 {
+			return nil, &apiError{http.StatusNotFound, fmt.Sprintf("no task with ID %d", id)}
+		}
+		tasks, err := change(tasks, id)
+		if err != nil {
+			return nil, err
+		}
+		changed = tasks[findTask(tasks, id)]
+		return tasks, nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changed)
+}
+
+func handleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	req, err := readTaskRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	changeTask(w, r, func(tasks []Task, id int) ([]Task, error) {
+		return applyTaskRequest(tasks, findTask(tasks, id), req, force)
+	})
+}
+
+func handleCompleteTask(w http.ResponseWriter, r *http.Request) {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	changeTask(w, r, func(tasks []Task, id int) ([]Task, error) {
+		children, blockers := openDependencies(tasks, id)
+		if !force && (len(children) > 0 || len(blockers) > 0) {
+			return nil, &apiError{http.StatusConflict, fmt.Sprintf("task %d has open subtasks or blockers; add ?force=true to complete it anyway", id)}
+		}
+		done, err := completeTask(tasks, id)
+		if err != nil {
+			return nil, conflict(tasks, id, err)
+		}
+		return done, nil
+	})
+}
+
+func handleReopenTask(w http.ResponseWriter, r *http.Request) {
+	changeTask(w, r, func(tasks []Task, id int) ([]Task, error) {
+		reopened, err := reopenTask(tasks, id)
+		if err != nil {
+			return nil, conflict(tasks, id, err)
+		}
+		return reopened, nil
+	})
+}
+
+func handleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	_, err = updateTasks(operationFor(r), func(tasks []Task) ([]Task, error) {
+		if findTask(tasks, id) < 0 {
+			return nil, &apiError{http.StatusNotFound, fmt.Sprintf("no task with ID %d", id)}
+		}
+		return removeTasks(tasks, []int{id}), nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveRequest calls a handler the way the mux would, with the ID path
+// value set, and returns the recorded response
+func serveRequest(t *testing.T, handler http.HandlerFunc, method, target, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if id != "" {
+		req.SetPathValue("id", id)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func decodeTask(t *testing.T, rec *httptest.ResponseRecorder) Task {
+	t.Helper()
+	var task Task
+	if err := json.NewDecoder(rec.Body).Decode(&task); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	return task
+}
+
+func TestServeTasks(t *testing.T) {
+	useTempTasksFile(t)
+
+	rec := serveRequest(t, handleCreateTask, "POST", "/api/tasks", "", `{"description": "Release +app", "priority": "high"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body)
+	}
+	if task := decodeTask(t, rec); task.ID != 1 || task.Priority != "A" || task.Status != "todo" {
+		t.Errorf("created %+v", task)
+	}
+	rec = serveRequest(t, handleCreateTask, "POST", "/api/tasks", "", `{"description": "Changelog", "parent": 1}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		id      string
+		body    string
+		want    int
+	}{
+		{name: "no description", handler: handleCreateTask, method: "POST", body: `{"priority": "A"}`, want: http.StatusBadRequest},
+		{name: "unknown field", handler: handleCreateTask, method: "POST", body: `{"description": "x", "colour": "red"}`, want: http.StatusBadRequest},
+		{name: "bad priority", handler: handleUpdateTask, method: "PATCH", id: "1", body: `{"priority": "Z"}`, want: http.StatusBadRequest},
+		{name: "unknown status", handler: handleUpdateTask, method: "PATCH", id: "1", body: `{"status": "blocked"}`, want: http.StatusBadRequest},
+		{name: "missing task", handler: handleGetTask, method: "GET", id: "9", want: http.StatusNotFound},
+		{name: "bad ID", handler: handleGetTask, method: "GET", id: "x", want: http.StatusBadRequest},
+		{name: "open subtask", handler: handleCompleteTask, method: "POST", id: "1", want: http.StatusConflict},
+		{name: "moved to done with an open subtask", handler: handleUpdateTask, method: "PATCH", id: "1", body: `{"status": "done"}`, want: http.StatusConflict},
+		{name: "reopen an open task", handler: handleReopenTask, method: "POST", id: "2", want: http.StatusConflict},
+		{name: "delete a missing task", handler: handleDeleteTask, method: "DELETE", id: "9", want: http.StatusNotFound},
+	} {
+		if rec := serveRequest(t, tt.handler, tt.method, "/api/tasks", tt.id, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	rec = serveRequest(t, handleUpdateTask, "PATCH", "/api/tasks/2", "2", `{"status": "doing", "due": "2099-01-31"}`)
+	if task := decodeTask(t, rec); rec.Code != http.StatusOK || task.Status != "doing" || task.Due == nil {
+		t.Errorf("update = %d, %+v", rec.Code, task)
+	}
+	rec = serveRequest(t, handleUpdateTask, "PATCH", "/api/tasks/1?force=true", "1", `{"status": "done"}`)
+	if task := decodeTask(t, rec); rec.Code != http.StatusOK || !task.isCompleted() {
+		t.Errorf("forced move to done = %d, %+v", rec.Code, task)
+	}
+	serveRequest(t, handleReopenTask, "POST", "/api/tasks/1/undone", "1", "")
+	rec = serveRequest(t, handleCompleteTask, "POST", "/api/tasks/1/done?force=true", "1", "")
+	if task := decodeTask(t, rec); rec.Code != http.StatusOK || !task.isCompleted() {
+		t.Errorf("forced done = %d, %+v", rec.Code, task)
+	}
+
+	rec = serveRequest(t, handleListTasks, "GET", "/api/tasks?filter=status:open", "", "")
+	var listed []Task
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || len(listed) != 1 || listed[0].ID != 2 {
+		t.Errorf("list of open tasks = %+v, %v", listed, err)
+	}
+	if rec := serveRequest(t, handleListTasks, "GET", "/api/tasks?filter=colour:red", "", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("list with a bad filter status = %d", rec.Code)
+	}
+
+	if rec := serveRequest(t, handleDeleteTask, "DELETE", "/api/tasks/2", "2", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d: %s", rec.Code, rec.Body)
+	}
+	if tasks, err := readTasks(); err != nil || len(tasks) != 1 || tasks[0].ID != 1 {
+		t.Errorf("tasks after the requests = %+v, %v", tasks, err)
+	}
+}
+
+func TestCreateTaskRejectedKeepsID(t *testing.T) {
+	useTempTasksFile(t)
+	for _, body := range []string{`{"description": "x", "priority": "Z"}`, `{"description": "x", "parent": 9}`} {
+		if rec := serveRequest(t, handleCreateTask, "POST", "/api/tasks", "", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("create %s status = %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+	// the rejected requests did not use up an ID
+	rec := serveRequest(t, handleCreateTask, "POST", "/api/tasks", "", `{"description": "Release"}`)
+	if task := decodeTask(t, rec); rec.Code != http.StatusCreated || task.ID != 1 {
+		t.Errorf("create after rejected ones = %d, %+v, want ID 1", rec.Code, task)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tt := range []struct {
+		method, origin string
+		want           int
+	}{
+		{method: http.MethodPost, want: http.StatusOK},
+		{method: http.MethodPost, origin: "http://127.0.0.1:8080", want: http.StatusOK},
+		{method: http.MethodPost, origin: "http://evil.example", want: http.StatusForbidden},
+		{method: http.MethodGet, origin: "http://evil.example", want: http.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, "/api/tasks", nil)
+		req.Host = "127.0.0.1:8080"
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		sameOrigin(ok).ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s from %q: status = %d, want %d", tt.method, tt.origin, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestLocalHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		addr   string
+		host   string
+		origin string
+		method string
+		want   int
+	}{
+		{name: "listen address", addr: "127.0.0.1:8080", host: "127.0.0.1:8080", want: http.StatusOK},
+		{name: "localhost", addr: "127.0.0.1:8080", host: "localhost:8080", want: http.StatusOK},
+		{name: "ipv6 loopback", addr: "127.0.0.1:8080", host: "[::1]:8080", want: http.StatusOK},
+		{name: "named listen address", addr: "tasks.lan:8080", host: "tasks.lan:8080", want: http.StatusOK},
+		{name: "rebound domain", addr: "127.0.0.1:8080", host: "evil.example:8080", want: http.StatusForbidden},
+		{
+			name: "rebound domain posting to itself", addr: "127.0.0.1:8080",
+			host: "evil.example:8080", origin: "http://evil.example:8080", method: http.MethodPost,
+			want: http.StatusForbidden,
+		},
+		{name: "other address", addr: "127.0.0.1:8080", host: "192.168.1.5:8080", want: http.StatusForbidden},
+		{name: "any interface by address", addr: "0.0.0.0:8080", host: "192.168.1.5:8080", want: http.StatusOK},
+		{name: "any interface by name", addr: ":8080", host: "evil.example:8080", want: http.StatusForbidden},
+		{
+			name: "other site", addr: "127.0.0.1:8080",
+			host: "127.0.0.1:8080", origin: "http://evil.example", method: http.MethodPost,
+			want: http.StatusForbidden,
+		},
+		{
+			name: "same site", addr: "127.0.0.1:8080",
+			host: "127.0.0.1:8080", origin: "http://127.0.0.1:8080", method: http.MethodPost,
+			want: http.StatusOK,
+		},
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/api/tasks", nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			localHost(tt.addr, sameOrigin(ok)).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestApplyTaskRequestParent(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Description: "Release", Status: "todo"},
+		{ID: 2, Description: "Build", Status: "todo", Parent: 1},
+		{ID: 3, Description: "Sign", Status: "todo", Parent: 2},
+	}
+	tests := []struct {
+		name    string
+		id      int
+		parent  int
+		wantErr string
+	}{
+		{name: "valid", id: 3, parent: 1},
+		{name: "clear", id: 3, parent: 0},
+		{name: "itself", id: 1, parent: 1, wantErr: "its own parent"},
+		{name: "cycle", id: 1, parent: 3, wantErr: "cycle"},
+		{name: "missing", id: 1, parent: 7, wantErr: "no task with ID 7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := tt.parent
+			got, err := applyTaskRequest(mustCloneTasks(t, tasks), findTask(tasks, tt.id), taskRequest{Parent: &parent}, false)
+			if tt.wantErr != "" {
+				apiErr, ok := err.(*apiError)
+				if !ok || apiErr.status != http.StatusBadRequest || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("applyTaskRequest() error = %v, want a bad request containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyTaskRequest() error = %v", err)
+			}
+			if p := got[findTask(got, tt.id)].Parent; p != tt.parent {
+				t.Errorf("parent = %d, want %d", p, tt.parent)
+			}
+		})
+	}
+}
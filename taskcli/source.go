@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	sourceMarkdown = "markdown" // a checklist item, see sync-md
+	sourceComment  = "comment"  // a TODO comment, see scan
+)
+
+// taskSource links a task to the line of a file it was imported from, so
+// that later syncs find it again and can write changes back
+type taskSource struct {
+	Kind    string `json:"kind"`              // sourceMarkdown or sourceComment
+	File    string `json:"file"`              // absolute path
+	Line    int    `json:"line"`              // 1-based, as of the last sync
+	Text    string `json:"text"`              // the line's text, to find it again when lines move
+	Checked bool   `json:"checked,omitempty"` // markdown: the checkbox as of the last sync
+	Gone    bool   `json:"gone,omitempty"`    // comment: removed from the file, which completed the task
+}
+
+// formatSource writes a source as one query-encoded word, for formats
+// that keep it in a single token
+func formatSource(src taskSource) string {
+	values := url.Values{}
+	values.Set("kind", src.Kind)
+	values.Set("file", src.File)
+	values.Set("line", strconv.Itoa(src.Line))
+	values.Set("text", src.Text)
+	if src.Checked {
+		values.Set("checked", "1")
+	}
+	if src.Gone {
+		values.Set("gone", "1")
+	}
+	return values.Encode()
+}
+
+func parseSource(s string) (*taskSource, error) {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	line, err := strconv.Atoi(values.Get("line"))
+	if err != nil || values.Get("kind") == "" || values.Get("file") == "" {
+		return nil, fmt.Errorf("invalid source %q", s)
+	}
+	return &taskSource{
+		Kind:    values.Get("kind"),
+		File:    values.Get("file"),
+		Line:    line,
+		Text:    values.Get("text"),
+		Checked: values.Get("checked") == "1",
+		Gone:    values.Get("gone") == "1",
+	}, nil
+}
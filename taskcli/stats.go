@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stats count the tasks file and its archive together, so that archiving
+// completed tasks does not change the history.
+
+const noProject = "(none)"
+
+type weekStats struct {
+	Week      string `json:"week"` // the Monday starting the week
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+type projectStats struct {
+	Project   string  `json:"project"`
+	Open      int     `json:"open"`
+	Completed int     `json:"completed"`
+	Created   int     `json:"created_in_period"`
+	Closed    int     `json:"completed_in_period"`
+	CycleDays float64 `json:"avg_cycle_days,omitempty"`
+}
+
+type burndownPoint struct {
+	Date string `json:"date"`
+	Open int    `json:"open"`
+}
+
+type statsReport struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"` // exclusive
+	Scope     string          `json:"scope,omitempty"`
+	Weeks     []weekStats     `json:"weeks"`
+	CycleDays float64         `json:"avg_cycle_days,omitempty"`
+	Cycles    int             `json:"cycles"` // completed tasks the average is over
+	Projects  []projectStats  `json:"projects"`
+	Burndown  []burndownPoint `json:"burndown"`
+}
+
+// statsTasks returns the tasks together with those in the archive
+func statsTasks(tasks []Task) ([]Task, error) {
+	if _, err := os.Stat(archiveFile()); err != nil {
+		return tasks, nil
+	}
+	archived, err := readTasksFile(archiveFile(), formatForPath(archiveFile()))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", archiveFile(), err)
+	}
+	return append(append([]Task{}, tasks...), archived...), nil
+}
+
+// cycleTime is how long a completed task took from creation to completion
+func cycleTime(task Task) (time.Duration, bool) {
+	if !task.isCompleted() || task.CreatedAt == nil || task.CompletedAt == nil {
+		return 0, false
+	}
+	return task.CompletedAt.Sub(*task.CreatedAt), true
+}
+
+func inDays(d time.Duration) float64 {
+	return d.Hours() / 24
+}
+
+// within reports whether t is set and in [from, to)
+func within(t *time.Time, from, to time.Time) bool {
+	return t != nil && !t.Before(from) && t.Before(to)
+}
+
+// buildStats counts the tasks matching the filter over the last n weeks,
+// this week included. A nil filter matches every task.
+func buildStats(tasks []Task, weeks int, filter taskFilter) statsReport {
+	_, to := thisWeek()
+	from := to.AddDate(0, 0, -7*weeks)
+	report := statsReport{From: from.Format(dateLayout), To: to.Format(dateLayout)}
+
+	var matched []Task
+	for _, task := range tasks {
+		if filter == nil || filter(task) {
+			matched = append(matched, task)
+		}
+	}
+
+	for w := 0; w < weeks; w++ {
+		start := from.AddDate(0, 0, 7*w)
+		end := start.AddDate(0, 0, 7)
+		week := weekStats{Week: start.Format(dateLayout)}
+		for _, task := range matched {
+			if within(task.CreatedAt, start, end) {
+				week.Created++
+			}
+			if task.isCompleted() && within(task.CompletedAt, start, end) {
+				week.Completed++
+			}
+		}
+		report.Weeks = append(report.Weeks, week)
+	}
+
+	// cycle times are of the tasks completed in the period
+	var total time.Duration
+	byProject := map[string]*projectStats{}
+	cycles := map[string]time.Duration{}
+	cycleCounts := map[string]int{}
+	for _, task := range matched {
+		projects := task.Projects
+		if len(projects) == 0 {
+			projects = []string{noProject}
+		}
+		closed := task.isCompleted() && within(task.CompletedAt, from, to)
+		cycle, ok := cycleTime(task)
+		ok = ok && closed
+		if ok {
+			total += cycle
+			report.Cycles++
+		}
+		for _, name := range projects {
+			p := byProject[name]
+			if p == nil {
+				p = &projectStats{Project: name}
+				byProject[name] = p
+			}
+			if task.isCompleted() {
+				p.Completed++
+			} else {
+				p.Open++
+			}
+			if within(task.CreatedAt, from, to) {
+				p.Created++
+			}
+			if closed {
+				p.Closed++
+			}
+			if ok {
+				cycles[name] += cycle
+				cycleCounts[name]++
+			}
+		}
+	}
+	if report.Cycles > 0 {
+		report.CycleDays = inDays(total / time.Duration(report.Cycles))
+	}
+	report.Projects = []projectStats{}
+	for name, p := range byProject {
+		if n := cycleCounts[name]; n > 0 {
+			p.CycleDays = inDays(cycles[name] / time.Duration(n))
+		}
+		report.Projects = append(report.Projects, *p)
+	}
+	sort.Slice(report.Projects, func(i, j int) bool {
+		a, b := report.Projects[i], report.Projects[j]
+		if (a.Project == noProject) != (b.Project == noProject) {
+			return b.Project == noProject
+		}
+		return a.Project < b.Project
+	})
+
+	// the burndown counts the tasks open at the end of each day; tasks
+	// without a creation time are taken to have always existed
+	for day := from; day.Before(to) && !day.After(today()); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		point := burndownPoint{Date: day.Format(dateLayout)}
+		for _, task := range matched {
+			if task.CreatedAt != nil && !task.CreatedAt.Before(end) {
+				continue
+			}
+			if task.isCompleted() && (task.CompletedAt == nil || task.CompletedAt.Before(end)) {
+				continue
+			}
+			point.Open++
+		}
+		report.Burndown = append(report.Burndown, point)
+	}
+	return report
+}
+
+// formatDays shows a duration in days as e.g. 3.5d, or in hours below a day
+func formatDays(d float64) string {
+	if d < 1 {
+		return fmt.Sprintf("%.1fh", d*24)
+	}
+	return fmt.Sprintf("%.1fd", d)
+}
+
+func printStats(report statsReport) {
+	title := "Tasks created and completed per week"
+	if report.Scope != "" {
+		title += " (" + report.Scope + ")"
+	}
+	fmt.Println(title + ":")
+	fmt.Printf("  %-12s %8s %10s\n", "week of", "created", "completed")
+	for _, week := range report.Weeks {
+		fmt.Printf("  %-12s %8d %10d\n", week.Week, week.Created, week.Completed)
+	}
+
+	fmt.Println()
+	if report.Cycles == 0 {
+		fmt.Println("Cycle time: no tasks completed in the period")
+	} else {
+		fmt.Printf("Cycle time: %s on average over %d completed task(s)\n", formatDays(report.CycleDays), report.Cycles)
+	}
+
+	if len(report.Projects) > 0 {
+		fmt.Println("\nBy project:")
+		width := len("project")
+		for _, p := range report.Projects {
+			width = max(width, len(p.Project))
+		}
+		fmt.Printf("  %-*s %6s %6s %8s %10s %10s\n", width, "project", "open", "done", "created", "completed", "avg cycle")
+		for _, p := range report.Projects {
+			cycle := "-"
+			if p.CycleDays > 0 {
+				cycle = formatDays(p.CycleDays)
+			}
+			fmt.Printf("  %-*s %6d %6d %8d %10d %10s\n", width, p.Project, p.Open, p.Completed, p.Created, p.Closed, cycle)
+		}
+	}
+
+	fmt.Println("\nBurndown (open tasks at the end of each day):")
+	for _, line := range burndownChart(report.Burndown, 10) {
+		fmt.Println("  " + line)
+	}
+}
+
+// burndownChart draws the open counts as columns of # at most height rows
+// high, with the dates of the first and last day under them
+func burndownChart(points []burndownPoint, height int) []string {
+	if len(points) == 0 {
+		return nil
+	}
+	top := 0
+	for _, p := range points {
+		top = max(top, p.Open)
+	}
+	height = max(min(height, top), 1)
+	label := len(fmt.Sprint(top))
+
+	var lines []string
+	for row := height; row >= 1; row-- {
+		var b strings.Builder
+		if row == height {
+			fmt.Fprintf(&b, "%*d |", label, top)
+		} else {
+			fmt.Fprintf(&b, "%*s |", label, "")
+		}
+		for _, p := range points {
+			// a column reaches this row if it covers the row's share of the top
+			if p.Open*height > (row-1)*top {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		lines = append(lines, strings.TrimRight(b.String(), " "))
+	}
+	lines = append(lines, fmt.Sprintf("%*d +%s", label, 0, strings.Repeat("-", len(points))))
+
+	first, last := points[0].Date[5:], points[len(points)-1].Date[5:]
+	axis := first
+	if gap := len(points) - len(first) - len(last); gap > 0 {
+		axis += strings.Repeat(" ", gap) + last
+	}
+	lines = append(lines, strings.Repeat(" ", label+2)+axis)
+	return lines
+}
+
+func writeStatsJSON(report statsReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
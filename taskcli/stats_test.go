@@ -0,0 +1,148 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statsFixture has tasks around the two weeks up to the end of this week,
+// with times given in days and hours from the Monday of last week
+func statsFixture() []Task {
+	from, _ := thisWeek()
+	from = from.AddDate(0, 0, -7)
+	at := func(day, hour int) *time.Time {
+		t := from.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+		return &t
+	}
+	tasks := []Task{
+		{ID: 1, Description: "Ship +web", Status: "done", CreatedAt: at(1, 9), CompletedAt: at(3, 9)},
+		{ID: 2, Description: "Old +web", Status: "done", CreatedAt: at(-3, 0), CompletedAt: at(7, 12)},
+		{ID: 3, Description: "Plan +api", Status: "doing", CreatedAt: at(2, 0)},
+		{ID: 4, Description: "Inbox", Status: "todo"},
+		{ID: 5, Description: "Shared +api +web", Status: "done", CreatedAt: at(7, 0), CompletedAt: at(8, 0)},
+		{ID: 6, Description: "Ancient +web", Status: "done", CreatedAt: at(-10, 0), CompletedAt: at(-5, 0)},
+	}
+	for i := range tasks {
+		parseDescription(&tasks[i])
+	}
+	return tasks
+}
+
+func TestBuildStats(t *testing.T) {
+	report := buildStats(statsFixture(), 2, nil)
+
+	from, to := thisWeek()
+	if report.From != from.AddDate(0, 0, -7).Format(dateLayout) || report.To != to.Format(dateLayout) {
+		t.Errorf("period = %s to %s", report.From, report.To)
+	}
+	wantWeeks := []weekStats{
+		{Week: report.From, Created: 2, Completed: 1},
+		{Week: from.Format(dateLayout), Created: 1, Completed: 2},
+	}
+	if !reflect.DeepEqual(report.Weeks, wantWeeks) {
+		t.Errorf("weeks = %+v, want %+v", report.Weeks, wantWeeks)
+	}
+
+	// 2, 10.5 and 1 days; task 6 was completed before the period
+	if report.Cycles != 3 || report.CycleDays != 4.5 {
+		t.Errorf("cycle time = %v over %d, want 4.5 over 3", report.CycleDays, report.Cycles)
+	}
+
+	wantProjects := []projectStats{
+		{Project: "api", Open: 1, Completed: 1, Created: 2, Closed: 1, CycleDays: 1},
+		{Project: "web", Completed: 4, Created: 2, Closed: 3, CycleDays: 4.5},
+		{Project: noProject, Open: 1},
+	}
+	if !reflect.DeepEqual(report.Projects, wantProjects) {
+		t.Errorf("projects =\n%+v\nwant\n%+v", report.Projects, wantProjects)
+	}
+
+	// last week is in the past whatever day it is today
+	var open []int
+	for _, point := range report.Burndown[:7] {
+		open = append(open, point.Open)
+	}
+	if want := []int{2, 3, 4, 3, 3, 3, 3}; !reflect.DeepEqual(open, want) {
+		t.Errorf("burndown of last week = %v, want %v", open, want)
+	}
+	if days := int(today().Sub(from).Hours()/24+0.5) + 8; len(report.Burndown) != days {
+		t.Errorf("burndown has %d days, want %d up to today", len(report.Burndown), days)
+	}
+	if last := report.Burndown[len(report.Burndown)-1]; last.Date != today().Format(dateLayout) {
+		t.Errorf("burndown ends on %s, want today", last.Date)
+	}
+}
+
+func TestBuildStatsFiltered(t *testing.T) {
+	filter, err := parseFilter("project:api", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := buildStats(statsFixture(), 1, filter)
+	if len(report.Weeks) != 1 || report.Weeks[0] != (weekStats{Week: report.From, Created: 1, Completed: 1}) {
+		t.Errorf("weeks = %+v", report.Weeks)
+	}
+	if report.Cycles != 1 || report.CycleDays != 1 {
+		t.Errorf("cycle time = %v over %d, want 1 over 1", report.CycleDays, report.Cycles)
+	}
+	// the shared task counts for web too, as the filter selects tasks
+	var names []string
+	for _, p := range report.Projects {
+		names = append(names, p.Project)
+	}
+	if !reflect.DeepEqual(names, []string{"api", "web"}) {
+		t.Errorf("projects = %v, want api and web", names)
+	}
+}
+
+func TestBurndownChart(t *testing.T) {
+	var points []burndownPoint
+	for i, open := range []int{4, 2, 0, 1} {
+		points = append(points, burndownPoint{Date: time.Date(2026, 10, 1+i, 0, 0, 0, 0, time.UTC).Format(dateLayout), Open: open})
+	}
+	want := []string{
+		"4 |#",
+		"  |#",
+		"  |##",
+		"  |## #",
+		"0 +----",
+		"   10-01",
+	}
+	if got := burndownChart(points, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("burndownChart() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// taller counts are scaled down, and long charts get the last date
+	points = nil
+	for i := 0; i < 12; i++ {
+		points = append(points, burndownPoint{Date: time.Date(2026, 10, 1+i, 0, 0, 0, 0, time.UTC).Format(dateLayout), Open: 40 - 2*i})
+	}
+	got := burndownChart(points, 4)
+	if len(got) != 6 || got[0] != "40 |#####" || got[3] != "   |############" || got[5] != "    10-01  10-12" {
+		t.Errorf("burndownChart() of 12 days =\n%s", strings.Join(got, "\n"))
+	}
+	if got := burndownChart(nil, 10); got != nil {
+		t.Errorf("burndownChart(nil) = %q, want nothing", got)
+	}
+}
+
+func TestStatsTasksIncludesArchive(t *testing.T) {
+	useTempTasksFile(t)
+	tasks := statsFixture()
+	if got, err := statsTasks(tasks); err != nil || len(got) != 6 {
+		t.Fatalf("statsTasks() without an archive = %d tasks, %v", len(got), err)
+	}
+	tasks, err := archiveTasks(tasks, []int{1, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := statsTasks(tasks)
+	if err != nil || len(all) != 6 {
+		t.Fatalf("statsTasks() with an archive = %d tasks, %v, want 6", len(all), err)
+	}
+	if !reflect.DeepEqual(buildStats(all, 2, nil), buildStats(statsFixture(), 2, nil)) {
+		t.Errorf("archiving changed the stats")
+	}
+}
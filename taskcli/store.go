@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// per-directory task lists, found by walking up from the working directory
+var localTaskFiles = []string{".tasks.json", ".tasks.txt"}
+
+// dataDir returns the XDG data directory for taskcli
+func dataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "taskcli"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "taskcli"), nil
+}
+
+// findLocalTaskFile walks up from dir looking for a per-directory task list
+func findLocalTaskFile(dir string) string {
+	for {
+		for _, name := range localTaskFiles {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// resolveTasksFile picks the tasks file, in order of precedence: the
+// --file flag, TASKCLI_FILE, a per-directory list in the working directory
+// or above it, a tasks.json in the working directory as older versions
+// used, and finally tasks.json in the XDG data directory.
+func resolveTasksFile(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if file := os.Getenv("TASKCLI_FILE"); file != "" {
+		return file, nil
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if local := findLocalTaskFile(cwd); local != "" {
+			return local, nil
+		}
+	}
+	if _, err := os.Stat("tasks.json"); err == nil {
+		return "tasks.json", nil
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tasks.json"), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a half-written file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+var errLockTimeout = errors.New("timed out waiting for another taskcli to finish")
+
+// lockWait is how long lockTasks waits for another taskcli to finish
+var lockWait = 10 * time.Second
+
+func lockFile() string {
+	return tasksFile + ".lock"
+}
+
+// lockTasks takes the advisory lock that serializes load-modify-save
+// cycles between taskcli processes. The returned function releases it.
+func lockTasks() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(lockFile()), 0755); err != nil {
+		return nil, err
+	}
+	return acquireLock(lockFile())
+}
+
+// updateTasks runs one locked load-modify-save cycle, the same as a single
+// CLI invocation, for commands that stay running and change tasks over time
+func updateTasks(op string, change func([]Task) ([]Task, error)) ([]Task, error) {
+	unlock, err := lockTasks()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	tasks, err := loadTasks()
+	if err != nil {
+		return nil, err
+	}
+	if tasks, err = change(tasks); err != nil {
+		return nil, err
+	}
+	operation = op
+	if err := saveTasks(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// readTasks loads the tasks under the lock, so it never sees another
+// process halfway through a change
+func readTasks() ([]Task, error) {
+	unlock, err := lockTasks()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return loadTasks()
+}
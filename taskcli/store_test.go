@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveTasksFile(t *testing.T) {
+	// root/project/sub is the working directory in each case
+	setup := func(t *testing.T, files ...string) string {
+		t.Helper()
+		root := t.TempDir()
+		cwd := filepath.Join(root, "project", "sub")
+		if err := os.MkdirAll(cwd, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if err := os.WriteFile(filepath.Join(root, file), []byte("[]"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		t.Chdir(cwd)
+		t.Setenv("XDG_DATA_HOME", filepath.Join(root, "data"))
+		t.Setenv("TASKCLI_FILE", "")
+		return root
+	}
+
+	tests := []struct {
+		name  string
+		files []string
+		flag  string
+		env   string
+		want  string // relative to the temporary root, or as given
+	}{
+		{name: "flag first", files: []string{"project/.tasks.json"}, flag: "/elsewhere/todo.txt", env: "/env/tasks.json", want: "/elsewhere/todo.txt"},
+		{name: "then the environment", files: []string{"project/.tasks.json"}, env: "/env/tasks.json", want: "/env/tasks.json"},
+		{name: "per-directory list in a parent", files: []string{"project/.tasks.json"}, want: "project/.tasks.json"},
+		{name: "todo.txt list", files: []string{"project/sub/.tasks.txt"}, want: "project/sub/.tasks.txt"},
+		{name: "nearest list wins", files: []string{".tasks.json", "project/.tasks.txt"}, want: "project/.tasks.txt"},
+		{name: "tasks.json in the working directory", files: []string{"project/sub/tasks.json"}, want: "tasks.json"},
+		{name: "XDG data directory", want: "data/taskcli/tasks.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := setup(t, tt.files...)
+			if tt.env != "" {
+				t.Setenv("TASKCLI_FILE", tt.env)
+			}
+			want := tt.want
+			if !filepath.IsAbs(want) && want != "tasks.json" {
+				want = filepath.Join(root, want)
+			}
+			got, err := resolveTasksFile(tt.flag)
+			if err != nil {
+				t.Fatalf("resolveTasksFile() error = %v", err)
+			}
+			// the temporary directory may be reached through a symlink
+			if gotReal, _ := filepath.EvalSymlinks(got); gotReal != "" {
+				got = gotReal
+			}
+			if wantReal, _ := filepath.EvalSymlinks(want); wantReal != "" {
+				want = wantReal
+			}
+			if got != want {
+				t.Errorf("resolveTasksFile(%q) = %q, want %q", tt.flag, got, want)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new", "tasks.json")
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Fatalf("file = %q, %v, want %q", data, err, content)
+		}
+	}
+	// nothing is left behind next to it
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory has %v, %v, want only tasks.json", entries, err)
+	}
+}
+
+func TestLockTasks(t *testing.T) {
+	old, oldWait := tasksFile, lockWait
+	tasksFile, lockWait = filepath.Join(t.TempDir(), "tasks.json"), 200*time.Millisecond
+	t.Cleanup(func() { tasksFile, lockWait = old, oldWait })
+
+	unlock, err := lockTasks()
+	if err != nil {
+		t.Fatalf("lockTasks() error = %v", err)
+	}
+	// a second taskcli gives up while the lock is held
+	if _, err := lockTasks(); !errors.Is(err, errLockTimeout) {
+		t.Fatalf("lockTasks() while locked: error = %v, want %v", err, errLockTimeout)
+	}
+
+	// and gets it once the first one is done
+	lockWait = 5 * time.Second
+	acquired := make(chan error)
+	go func() {
+		unlockSecond, err := lockTasks()
+		if err == nil {
+			unlockSecond()
+		}
+		acquired <- err
+	}()
+	select {
+	case err := <-acquired:
+		t.Fatalf("lockTasks() returned %v while the lock was held", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	if err := <-acquired; err != nil {
+		t.Fatalf("lockTasks() after unlock: error = %v", err)
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sync reconciles the tasks with a taskcli server, so several machines can
+// share one list and edit it offline. Tasks are matched by UID, as local
+// IDs differ between machines. The server numbers every change it accepts
+// with a revision, and a client remembers the revision and content each
+// task had when it last synced. That tells which tasks changed locally and
+// which version a change was made on. When a task changed on both sides,
+// the version modified last wins and the conflict is reported.
+
+// syncRecord is one version of a task as the server keeps it
+type syncRecord struct {
+	UID string `json:"uid"`
+	// Rev is the revision the server gave this version; in a push, the
+	// revision the change was made on, 0 for a new task
+	Rev       int       `json:"rev"`
+	Modified  time.Time `json:"modified"`         // when the change was made
+	Origin    string    `json:"origin,omitempty"` // host that made it
+	Deleted   bool      `json:"deleted,omitempty"`
+	Task      *Task     `json:"task,omitempty"`       // without its local ID, relations and source
+	Parent    string    `json:"parent,omitempty"`     // UID of the parent task
+	BlockedBy []string  `json:"blocked_by,omitempty"` // UIDs of the blockers
+}
+
+// hash identifies a version by its content, leaving out where and when it
+// was sent
+func (r syncRecord) hash() string {
+	data, _ := json.Marshal(struct {
+		Deleted   bool
+		Task      *Task
+		Parent    string
+		BlockedBy []string
+	}{r.Deleted, r.Task, r.Parent, r.BlockedBy})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type pullResponse struct {
+	Rev     int          `json:"rev"`
+	Records []syncRecord `json:"records"`
+}
+
+type pushRequest struct {
+	Changes []syncRecord `json:"changes"`
+}
+
+const (
+	pushApplied = "applied" // nobody else changed the task in between
+	pushWon     = "won"     // somebody did, but this change is newer
+	pushLost    = "lost"    // somebody made a newer change
+)
+
+type pushResult struct {
+	UID    string     `json:"uid"` // of the change this answers
+	Status string     `json:"status"`
+	Record syncRecord `json:"record"` // the server's version after the push
+}
+
+type pushResponse struct {
+	Rev     int          `json:"rev"`
+	Results []pushResult `json:"results"`
+}
+
+// syncState is what a client remembers between syncs, in a file next to
+// the tasks file
+type syncState struct {
+	Server string              `json:"server"`
+	Rev    int                 `json:"rev"`   // server revision pulled up to
+	Tasks  map[string]syncBase `json:"tasks"` // by UID
+}
+
+// syncBase is a task as of the last sync
+type syncBase struct {
+	Rev  int    `json:"rev"`
+	Hash string `json:"hash"`
+}
+
+type syncReport struct {
+	pulled, pushed int
+	conflicts      []string
+}
+
+func syncStateFile() string {
+	return tasksFile + ".sync"
+}
+
+// readSyncState loads the state of the last sync with server. Syncing
+// with another server starts over, pushing every task.
+func readSyncState(server string) (syncState, error) {
+	state := syncState{Server: server, Tasks: map[string]syncBase{}}
+	data, err := os.ReadFile(syncStateFile())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	var saved syncState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return state, fmt.Errorf("%s: %w", syncStateFile(), err)
+	}
+	if saved.Server != server {
+		return state, nil
+	}
+	if saved.Tasks == nil {
+		saved.Tasks = map[string]syncBase{}
+	}
+	return saved, nil
+}
+
+func writeSyncState(state syncState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(syncStateFile(), data)
+}
+
+type syncClient struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+func newSyncClient(server, token string) syncClient {
+	return syncClient{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request with an optional JSON body and decodes the answer
+func (c syncClient) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s: %s", c.server, apiErr.Error)
+		}
+		return fmt.Errorf("%s: %s", c.server, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func newUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "taskcli-" + hex.EncodeToString(b)
+}
+
+// findTaskByStoredUID is findTaskByUID for UIDs the task has stored, which
+// all tasks have once synced
+func findTaskByStoredUID(tasks []Task, uid string) int {
+	for i, task := range tasks {
+		if task.UID == uid {
+			return i
+		}
+	}
+	return -1
+}
+
+// syncRecordFor turns a task into the version sent to the server, with
+// its relations as UIDs. The source is left out, as the file it names is
+// on this machine.
+func syncRecordFor(tasks []Task, task Task) syncRecord {
+	uid := func(id int) string {
+		if i := findTask(tasks, id); i >= 0 {
+			return tasks[i].UID
+		}
+		return ""
+	}
+	shared := task
+	shared.ID, shared.Parent, shared.BlockedBy, shared.Source = 0, 0, nil, nil
+	rec := syncRecord{UID: task.UID, Task: &shared, Parent: uid(task.Parent)}
+	for _, id := range task.BlockedBy {
+		if blocker := uid(id); blocker != "" {
+			rec.BlockedBy = append(rec.BlockedBy, blocker)
+		}
+	}
+	switch {
+	case task.ModifiedAt != nil:
+		rec.Modified = *task.ModifiedAt
+	case task.CreatedAt != nil:
+		rec.Modified = *task.CreatedAt
+	}
+	return rec
+}
+
+// localRecord is the local version of a task known to the server: nil if
+// it is not known here, a deletion if it was removed since the last sync
+func localRecord(tasks []Task, state syncState, uid string) *syncRecord {
+	if i := findTaskByStoredUID(tasks, uid); i >= 0 {
+		rec := syncRecordFor(tasks, tasks[i])
+		return &rec
+	}
+	if _, known := state.Tasks[uid]; known {
+		return &syncRecord{UID: uid, Deleted: true}
+	}
+	return nil
+}
+
+// applyRecord replaces the local version of a task with the server's.
+// Relations are set by resolveRelations once every record is applied.
+func applyRecord(tasks []Task, rec syncRecord) []Task {
+	i := findTaskByStoredUID(tasks, rec.UID)
+	if rec.Deleted {
+		if i >= 0 {
+			tasks = removeTasks(tasks, []int{tasks[i].ID})
+		}
+		return tasks
+	}
+	task := *rec.Task
+	task.UID = rec.UID
+	if i >= 0 {
+		task.ID, task.Source = tasks[i].ID, tasks[i].Source
+		tasks[i] = task
+		return tasks
+	}
+	task.ID = nextID(tasks)
+	return append(tasks, task)
+}
+
+// resolveRelations sets the parent and blockers of tasks taken from the
+// server, from the UIDs they came with
+func resolveRelations(tasks []Task, applied []syncRecord) {
+	id := func(uid string) int {
+		if i := findTaskByStoredUID(tasks, uid); uid != "" && i >= 0 {
+			return tasks[i].ID
+		}
+		return 0
+	}
+	for _, rec := range applied {
+		i := findTaskByStoredUID(tasks, rec.UID)
+		if rec.Deleted || i < 0 {
+			continue
+		}
+		tasks[i].Parent = id(rec.Parent)
+		tasks[i].BlockedBy = nil
+		for _, uid := range rec.BlockedBy {
+			if blocker := id(uid); blocker != 0 {
+				tasks[i].BlockedBy = append(tasks[i].BlockedBy, blocker)
+			}
+		}
+	}
+}
+
+// describeRecord names a task in a conflict report
+func describeRecord(tasks []Task, local, remote syncRecord) string {
+	if i := findTaskByStoredUID(tasks, remote.UID); i >= 0 {
+		return fmt.Sprintf("task %d (%s)", tasks[i].ID, truncate(tasks[i].Description, 40))
+	}
+	for _, rec := range []syncRecord{remote, local} {
+		if rec.Task != nil {
+			return fmt.Sprintf("task %q", truncate(rec.Task.Description, 40))
+		}
+	}
+	return "task " + remote.UID
+}
+
+// resolveConflict picks between a task changed both locally and on the
+// server: an edit wins over a deletion, otherwise the newer change wins
+func resolveConflict(tasks []Task, local, remote syncRecord) (bool, string) {
+	what := describeRecord(tasks, local, remote)
+	origin := remote.Origin
+	if origin == "" {
+		origin = "another machine"
+	}
+	switch {
+	case remote.Deleted:
+		return false, fmt.Sprintf("%s was deleted on %s but changed here; kept it", what, origin)
+	case local.Deleted:
+		return true, fmt.Sprintf("%s was deleted here but changed on %s; restored it", what, origin)
+	case remote.Modified.After(local.Modified):
+		return true, fmt.Sprintf("%s was changed here and on %s; kept the newer version from %s", what, origin, origin)
+	default:
+		return false, fmt.Sprintf("%s was changed here and on %s; kept the newer version from here", what, origin)
+	}
+}
+
+// syncTasks pulls the changes made on the server since the last sync,
+// then pushes the local ones, and returns the reconciled tasks and the
+// state to remember for next time
+func syncTasks(tasks []Task, client syncClient, state syncState) ([]Task, syncState, syncReport, error) {
+	var report syncReport
+	origin, _ := os.Hostname()
+
+	// tasks get a UID the first time they are synced. That alone is not a
+	// change, so stampModified leaves their modification time as it was.
+	// A task without one counts as changed when it was created, so that
+	// the machines that pull it do not stamp it as changed there.
+	for i := range tasks {
+		if tasks[i].UID == "" {
+			tasks[i].UID = newUID()
+		}
+		if tasks[i].ModifiedAt == nil && tasks[i].CreatedAt != nil {
+			created := *tasks[i].CreatedAt
+			tasks[i].ModifiedAt = &created
+		}
+	}
+
+	var pull pullResponse
+	if err := client.do(http.MethodGet, fmt.Sprintf("/sync?since=%d", state.Rev), nil, &pull); err != nil {
+		return nil, state, report, err
+	}
+	if pull.Rev < state.Rev {
+		// the server lost its history, so start over as with a new one
+		state = syncState{Server: state.Server, Tasks: map[string]syncBase{}}
+		if err := client.do(http.MethodGet, "/sync?since=0", nil, &pull); err != nil {
+			return nil, state, report, err
+		}
+	}
+	// local changes are judged against the tasks before the pull, as
+	// removing a task also changes the tasks that referred to it
+	before, err := cloneTasks(tasks)
+	if err != nil {
+		return nil, state, report, err
+	}
+	var applied []syncRecord
+	for _, remote := range pull.Records {
+		base, known := state.Tasks[remote.UID]
+		local := localRecord(before, state, remote.UID)
+		switch {
+		case local == nil && remote.Deleted:
+		case local != nil && local.hash() == remote.hash():
+		case known && remote.hash() == base.Hash:
+			// this machine's own push, already applied here
+		case local == nil || (known && local.hash() == base.Hash):
+			tasks = applyRecord(tasks, remote)
+			applied = append(applied, remote)
+			report.pulled++
+		default:
+			takeRemote, msg := resolveConflict(tasks, *local, remote)
+			report.conflicts = append(report.conflicts, msg)
+			if !takeRemote {
+				// pushed below as a change to the server's version
+				state.Tasks[remote.UID] = syncBase{Rev: remote.Rev, Hash: remote.hash()}
+				continue
+			}
+			tasks = applyRecord(tasks, remote)
+			applied = append(applied, remote)
+		}
+		state.Tasks[remote.UID] = syncBase{Rev: remote.Rev, Hash: remote.hash()}
+	}
+	resolveRelations(tasks, applied)
+	state.Rev = pull.Rev
+	// tasks that lost a parent or blocker to a deletion changed here too
+	stampModified(loadedTasks, tasks)
+
+	var changes []syncRecord
+	for _, task := range tasks {
+		rec := syncRecordFor(tasks, task)
+		base, known := state.Tasks[task.UID]
+		if known && base.Hash == rec.hash() {
+			continue
+		}
+		rec.Rev, rec.Origin = base.Rev, origin
+		changes = append(changes, rec)
+	}
+	now := time.Now().Truncate(time.Second)
+	tombstone := syncRecord{Deleted: true}.hash()
+	var deleted []string
+	for uid, base := range state.Tasks {
+		if base.Hash != tombstone && findTaskByStoredUID(tasks, uid) < 0 {
+			deleted = append(deleted, uid)
+		}
+	}
+	sort.Strings(deleted)
+	for _, uid := range deleted {
+		changes = append(changes, syncRecord{UID: uid, Rev: state.Tasks[uid].Rev, Modified: now, Origin: origin, Deleted: true})
+	}
+	if len(changes) == 0 {
+		return tasks, state, report, nil
+	}
+
+	var push pushResponse
+	if err := client.do(http.MethodPost, "/sync", pushRequest{Changes: changes}, &push); err != nil {
+		return nil, state, report, err
+	}
+	pushed := map[string]syncRecord{}
+	for _, change := range changes {
+		pushed[change.UID] = change
+	}
+	applied = nil
+	for _, result := range push.Results {
+		change, ok := pushed[result.UID]
+		if !ok {
+			return nil, state, report, fmt.Errorf("%s answered for task %q, which was not pushed", client.server, result.UID)
+		}
+		rec := result.Record
+		switch result.Status {
+		case pushApplied:
+			report.pushed++
+		case pushWon:
+			report.pushed++
+			report.conflicts = append(report.conflicts, fmt.Sprintf("%s was also changed on another machine while syncing; kept the newer version from here",
+				describeRecord(tasks, change, rec)))
+		case pushLost:
+			report.conflicts = append(report.conflicts, fmt.Sprintf("%s was also changed on %s while syncing; kept the newer version from %s",
+				describeRecord(tasks, change, rec), rec.Origin, rec.Origin))
+			tasks = applyRecord(tasks, rec)
+			applied = append(applied, rec)
+		}
+		state.Tasks[rec.UID] = syncBase{Rev: rec.Rev, Hash: rec.hash()}
+	}
+	resolveRelations(tasks, applied)
+	return tasks, state, report, nil
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSyncServer is a sync server that can run something of the test's
+// between a client's pull and its push, and change its answer to a push
+type testSyncServer struct {
+	*httptest.Server
+	store *syncServer
+
+	mu         sync.Mutex
+	beforePush func()
+	answerPush func(*pushResponse)
+}
+
+func newTestSyncServer(t *testing.T) *testSyncServer {
+	t.Helper()
+	srv := &testSyncServer{store: &syncServer{
+		path:  filepath.Join(t.TempDir(), "server.json"),
+		token: "secret",
+		store: syncStore{Records: map[string]syncRecord{}},
+	}}
+	handler := srv.store.handler()
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		srv.mu.Lock()
+		hook, answer := srv.beforePush, srv.answerPush
+		srv.beforePush, srv.answerPush = nil, nil
+		srv.mu.Unlock()
+		if hook != nil {
+			hook()
+		}
+		if answer == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		var resp pushResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			writeError(w, err)
+			return
+		}
+		answer(&resp)
+		writeJSON(w, rec.Code, resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// onNextPush runs f once, before the server handles the next push
+func (srv *testSyncServer) onNextPush(f func()) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.beforePush = f
+}
+
+// answerNextPush has f change the server's answer to the next push
+func (srv *testSyncServer) answerNextPush(f func(*pushResponse)) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.answerPush = f
+}
+
+func (srv *testSyncServer) client() syncClient {
+	return newSyncClient(srv.URL, "secret")
+}
+
+// syncMachine is one client of the server, with its tasks as saved and
+// what it remembers of the last sync
+type syncMachine struct {
+	name  string
+	tasks []Task
+	state syncState
+}
+
+func newSyncMachine(name string, srv *testSyncServer) *syncMachine {
+	return &syncMachine{name: name, tasks: []Task{}, state: syncState{Server: srv.URL, Tasks: map[string]syncBase{}}}
+}
+
+// trySync runs a sync as the sync command does, with the machine's tasks
+// as the ones loaded from its file
+func (m *syncMachine) trySync(srv *testSyncServer) (syncReport, error) {
+	loaded, err := cloneTasks(m.tasks)
+	if err != nil {
+		return syncReport{}, err
+	}
+	// a sync that fails leaves the machine's tasks as they were
+	working, err := cloneTasks(m.tasks)
+	if err != nil {
+		return syncReport{}, err
+	}
+	loadedTasks, highestID = loaded, 0
+	tasks, state, report, err := syncTasks(working, srv.client(), m.state)
+	if err != nil {
+		return report, err
+	}
+	m.tasks, m.state = tasks, state
+	return report, nil
+}
+
+func (m *syncMachine) sync(t *testing.T, srv *testSyncServer) syncReport {
+	t.Helper()
+	report, err := m.trySync(srv)
+	if err != nil {
+		t.Fatalf("%s: syncTasks() error = %v", m.name, err)
+	}
+	return report
+}
+
+func (m *syncMachine) add(description string) {
+	m.tasks = addTask(m.tasks, description)
+}
+
+// find returns the index of the task with the given description
+func (m *syncMachine) find(t *testing.T, description string) int {
+	t.Helper()
+	for i, task := range m.tasks {
+		if task.Description == description {
+			return i
+		}
+	}
+	t.Fatalf("%s has no task %q: %v", m.name, description, m.descriptions())
+	return -1
+}
+
+// edit changes a task's description as of the given time
+func (m *syncMachine) edit(t *testing.T, from, to string, at time.Time) {
+	t.Helper()
+	i := m.find(t, from)
+	m.tasks[i].Description = to
+	m.tasks[i].ModifiedAt = &at
+	parseDescription(&m.tasks[i])
+}
+
+func (m *syncMachine) remove(t *testing.T, description string) {
+	t.Helper()
+	m.tasks = removeTasks(m.tasks, []int{m.tasks[m.find(t, description)].ID})
+}
+
+func (m *syncMachine) descriptions() []string {
+	var names []string
+	for _, task := range m.tasks {
+		names = append(names, task.Description)
+	}
+	return names
+}
+
+func checkReport(t *testing.T, who string, got syncReport, pulled, pushed, conflicts int) {
+	t.Helper()
+	if got.pulled != pulled || got.pushed != pushed || len(got.conflicts) != conflicts {
+		t.Errorf("%s: pulled %d, pushed %d, %d conflict(s) %q, want %d, %d, %d",
+			who, got.pulled, got.pushed, len(got.conflicts), got.conflicts, pulled, pushed, conflicts)
+	}
+}
+
+func checkDescriptions(t *testing.T, m *syncMachine, want ...string) {
+	t.Helper()
+	got := m.descriptions()
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("%s has %q, want %q", m.name, got, want)
+	}
+}
+
+func TestSyncPullAndPush(t *testing.T) {
+	useTempTasksFile(t)
+	srv := newTestSyncServer(t)
+	a, b := newSyncMachine("a", srv), newSyncMachine("b", srv)
+
+	a.add("Write report")
+	a.add("Send report")
+	a.tasks, _ = addBlocker(a.tasks, 2, 1)
+	a.add("Proofread")
+	a.tasks[2].Parent = 1
+	checkReport(t, "first push", a.sync(t, srv), 0, 3, 0)
+	if srv.store.store.Rev != 3 {
+		t.Errorf("server revision = %d, want 3", srv.store.store.Rev)
+	}
+
+	// another machine gets the tasks with their relations in its own IDs
+	b.add("Local errand")
+	checkReport(t, "first pull", b.sync(t, srv), 3, 1, 0)
+	checkDescriptions(t, b, "Local errand", "Write report", "Send report", "Proofread")
+	write, send, proofread := b.tasks[b.find(t, "Write report")], b.tasks[b.find(t, "Send report")], b.tasks[b.find(t, "Proofread")]
+	if len(send.BlockedBy) != 1 || send.BlockedBy[0] != write.ID || proofread.Parent != write.ID {
+		t.Errorf("relations not resolved: %q blocked by %v, %q has parent %d", send.Description, send.BlockedBy, proofread.Description, proofread.Parent)
+	}
+
+	// nothing changed, nothing is sent, and a machine's own push does not
+	// come back as a change
+	checkReport(t, "no changes", b.sync(t, srv), 0, 0, 0)
+	checkReport(t, "own push pulled", a.sync(t, srv), 1, 0, 0)
+	checkReport(t, "in step", a.sync(t, srv), 0, 0, 0)
+
+	// a minute on, so the edit cannot share a second with the task's
+	// previous change and pass for a change made on a as well
+	b.edit(t, "Write report", "Write the report", time.Now().Truncate(time.Second).Add(time.Minute))
+	checkReport(t, "edit pushed", b.sync(t, srv), 0, 1, 0)
+	checkReport(t, "edit pulled", a.sync(t, srv), 1, 0, 0)
+	checkDescriptions(t, a, "Write the report", "Send report", "Proofread", "Local errand")
+
+	// the state remembers every task at the server's revision
+	if a.state.Rev != srv.store.store.Rev || len(a.state.Tasks) != 4 {
+		t.Errorf("state at revision %d with %d tasks, want %d with 4", a.state.Rev, len(a.state.Tasks), srv.store.store.Rev)
+	}
+}
+
+func TestSyncRefusesWrongToken(t *testing.T) {
+	useTempTasksFile(t)
+	srv := newTestSyncServer(t)
+	loadedTasks = []Task{}
+	_, _, _, err := syncTasks([]Task{}, newSyncClient(srv.URL, "wrong"), syncState{Tasks: map[string]syncBase{}})
+	if err == nil || !strings.Contains(err.Error(), "token") {
+		t.Errorf("syncTasks() error = %v, want a token error", err)
+	}
+}
+
+func TestSyncKeepsModificationTimes(t *testing.T) {
+	useTempTasksFile(t)
+	srv := newTestSyncServer(t)
+	a, b := newSyncMachine("a", srv), newSyncMachine("b", srv)
+	edited := time.Date(2026, 9, 1, 10, 0, 0, 0, time.Local)
+	created := time.Date(2026, 8, 1, 9, 0, 0, 0, time.Local)
+	a.tasks = []Task{
+		{ID: 1, Description: "Edited", Status: "todo", CreatedAt: &created, ModifiedAt: &edited},
+		{ID: 2, Description: "Never edited", Status: "todo", CreatedAt: &created},
+	}
+
+	// getting a UID on the first sync is not an edit
+	checkReport(t, "first push", a.sync(t, srv), 0, 2, 0)
+	checkReport(t, "first pull", b.sync(t, srv), 2, 0, 0)
+	for _, m := range []*syncMachine{a, b} {
+		for _, want := range []struct {
+			description string
+			modified    time.Time
+		}{{"Edited", edited}, {"Never edited", created}} {
+			task := m.tasks[m.find(t, want.description)]
+			if task.ModifiedAt == nil || !task.ModifiedAt.Equal(want.modified) {
+				t.Errorf("%s: %q modified at %v, want %v", m.name, want.description, task.ModifiedAt, want.modified)
+			}
+		}
+	}
+	checkReport(t, "a in step", a.sync(t, srv), 0, 0, 0)
+}
+
+func TestSyncMatchesPushResultsByUID(t *testing.T) {
+	useTempTasksFile(t)
+	srv := newTestSyncServer(t)
+	a := newSyncMachine("a", srv)
+	a.add("Book flights")
+	a.add("Book hotel")
+	a.sync(t, srv)
+
+	a.edit(t, "Book flights", "Book flights for two", time.Now().Truncate(time.Second).Add(time.Minute))
+	a.edit(t, "Book hotel", "Book hotel by the sea", time.Now().Truncate(time.Second).Add(time.Minute))
+	srv.answerNextPush(func(resp *pushResponse) {
+		for i, j := 0, len(resp.Results)-1; i < j; i, j = i+1, j-1 {
+			resp.Results[i], resp.Results[j] = resp.Results[j], resp.Results[i]
+		}
+	})
+	checkReport(t, "answered in another order", a.sync(t, srv), 0, 2, 0)
+	for _, task := range a.tasks {
+		if base := a.state.Tasks[task.UID]; base.Hash != srv.store.store.Records[task.UID].hash() {
+			t.Errorf("state of %q does not match the server", task.Description)
+		}
+	}
+
+	a.edit(t, "Book hotel by the sea", "Book a hotel", time.Now().Truncate(time.Second).Add(2*time.Minute))
+	srv.answerNextPush(func(resp *pushResponse) { resp.Results[0].UID = "taskcli-unknown" })
+	if _, err := a.trySync(srv); err == nil || !strings.Contains(err.Error(), "not pushed") {
+		t.Errorf("syncTasks() with an answer for another task: error = %v", err)
+	}
+}
+
+// TestSyncPushConflict has another machine push a change to the same task
+// between this machine's pull and push, so the server sees the conflict
+func TestSyncPushConflict(t *testing.T) {
+	earlier := time.Now().Truncate(time.Second).Add(time.Minute)
+	later := earlier.Add(time.Minute)
+	tests := []struct {
+		name         string
+		aTime, bTime time.Time
+		want         string
+	}{
+		{name: "push loses", aTime: earlier, bTime: later, want: "edited on b"},
+		{name: "push wins", aTime: later, bTime: earlier, want: "edited on a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempTasksFile(t)
+			srv := newTestSyncServer(t)
+			a, b := newSyncMachine("a", srv), newSyncMachine("b", srv)
+			a.add("Book flights")
+			a.sync(t, srv)
+			b.sync(t, srv)
+
+			a.edit(t, "Book flights", "edited on a", tt.aTime)
+			b.edit(t, "Book flights", "edited on b", tt.bTime)
+			// runs on the server's goroutine, so it is checked afterwards
+			var bReport syncReport
+			var bErr error
+			srv.onNextPush(func() { bReport, bErr = b.trySync(srv) })
+			report := a.sync(t, srv)
+			if bErr != nil {
+				t.Fatalf("b: syncTasks() error = %v", bErr)
+			}
+			checkReport(t, "b pushes first", bReport, 0, 1, 0)
+			if len(report.conflicts) != 1 || !strings.Contains(report.conflicts[0], "while syncing") {
+				t.Fatalf("conflicts = %q, want one found by the server", report.conflicts)
+			}
+			checkDescriptions(t, a, tt.want)
+			if rec := srv.store.store.Records[a.tasks[0].UID]; rec.Task.Description != tt.want {
+				t.Errorf("server has %q, want %q", rec.Task.Description, tt.want)
+			}
+
+			// both machines end up with the newer version, and stay there
+			b.sync(t, srv)
+			checkDescriptions(t, b, tt.want)
+			checkReport(t, "a in step", a.sync(t, srv), 0, 0, 0)
+			checkReport(t, "b in step", b.sync(t, srv), 0, 0, 0)
+		})
+	}
+}
+
+// TestSyncDeleteAndEdit deletes a task on one machine and edits it on the
+// other: the edit wins, whichever syncs first
+func TestSyncDeleteAndEdit(t *testing.T) {
+	tests := []struct {
+		name         string
+		deleterFirst bool
+	}{
+		{name: "deletion synced first", deleterFirst: true},
+		{name: "edit synced first", deleterFirst: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempTasksFile(t)
+			srv := newTestSyncServer(t)
+			deleter, editor := newSyncMachine("deleter", srv), newSyncMachine("editor", srv)
+			deleter.add("Renew passport")
+			deleter.add("Pack")
+			deleter.sync(t, srv)
+			editor.sync(t, srv)
+
+			deleter.remove(t, "Renew passport")
+			editor.edit(t, "Renew passport", "Renew passport +travel", time.Now().Truncate(time.Second))
+			first, second := editor, deleter
+			if tt.deleterFirst {
+				first, second = deleter, editor
+			}
+			checkReport(t, first.name+" first", first.sync(t, srv), 0, 1, 0)
+			report := second.sync(t, srv)
+			if len(report.conflicts) != 1 || !strings.Contains(report.conflicts[0], "deleted") {
+				t.Errorf("%s: conflicts = %q, want one about the deletion", second.name, report.conflicts)
+			}
+
+			// the edited task is back everywhere
+			first.sync(t, srv)
+			for _, m := range []*syncMachine{deleter, editor} {
+				if i := m.find(t, "Renew passport +travel"); m.tasks[i].Projects[0] != "travel" {
+					t.Errorf("%s: task = %+v", m.name, m.tasks[i])
+				}
+				if len(m.tasks) != 2 {
+					t.Errorf("%s has %q, want both tasks", m.name, m.descriptions())
+				}
+			}
+			if rec := srv.store.store.Records[editor.tasks[editor.find(t, "Renew passport +travel")].UID]; rec.Deleted {
+				t.Errorf("server kept the deletion")
+			}
+			checkReport(t, "deleter in step", deleter.sync(t, srv), 0, 0, 0)
+			checkReport(t, "editor in step", editor.sync(t, srv), 0, 0, 0)
+		})
+	}
+}
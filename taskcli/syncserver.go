@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// server is the other end of sync. It keeps the latest version of every
+// task, deleted ones as tombstones so that a machine that was offline for
+// long still learns of the deletion, in one JSON file it rewrites after
+// each push. It does not touch a tasks file of its own.
+
+// syncStore is the server's file
+type syncStore struct {
+	Rev     int                   `json:"rev"`
+	Records map[string]syncRecord `json:"records"` // by UID
+}
+
+type syncServer struct {
+	mu    sync.Mutex
+	path  string
+	token string
+	store syncStore
+}
+
+// syncServerFile is where the server keeps its tasks by default
+func syncServerFile() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "server.json"), nil
+}
+
+func runSyncServer(addr, path, token string) error {
+	s := &syncServer{path: path, token: token, store: syncStore{Records: map[string]syncRecord{}}}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &s.store); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if s.store.Records == nil {
+			s.store.Records = map[string]syncRecord{}
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	fmt.Printf("Sync server for %s (revision %d) on http://%s\n", path, s.store.Rev, addr)
+	if token == "" {
+		fmt.Println("No --token given: anyone who can reach the server can change its tasks")
+	}
+	return http.ListenAndServe(addr, s.handler())
+}
+
+func (s *syncServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sync", s.handlePull)
+	mux.HandleFunc("POST /sync", s.handlePush)
+	return s.authorize(mux)
+}
+
+// authorize refuses requests without the token, if the server has one
+func (s *syncServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				writeError(w, &apiError{http.StatusUnauthorized, "missing or wrong token"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handlePull answers with the versions newer than the since revision
+func (s *syncServer) handlePull(w http.ResponseWriter, r *http.Request) {
+	since := 0
+	if value := r.URL.Query().Get("since"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, &apiError{http.StatusBadRequest, fmt.Sprintf("invalid revision %q", value)})
+			return
+		}
+		since = n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := pullResponse{Rev: s.store.Rev, Records: []syncRecord{}}
+	for _, rec := range s.store.Records {
+		if rec.Rev > since {
+			resp.Records = append(resp.Records, rec)
+		}
+	}
+	sort.Slice(resp.Records, func(i, j int) bool { return resp.Records[i].Rev < resp.Records[j].Rev })
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handlePush takes a client's changes. A change made on an older revision
+// than the server has is a conflict, which the newer change wins.
+func (s *syncServer) handlePush(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, &apiError{http.StatusUnsupportedMediaType, "requests must be application/json"})
+		return
+	}
+	var req pushRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, &apiError{http.StatusBadRequest, "invalid request body: " + err.Error()})
+		return
+	}
+	for _, change := range req.Changes {
+		if change.UID == "" || (!change.Deleted && change.Task == nil) {
+			writeError(w, &apiError{http.StatusBadRequest, "every change needs a uid and a task or deleted"})
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// changes are applied to a copy, kept only once it is saved
+	store := syncStore{Rev: s.store.Rev, Records: maps.Clone(s.store.Records)}
+	resp := pushResponse{Results: []pushResult{}}
+	for _, change := range req.Changes {
+		current, exists := store.Records[change.UID]
+		status := pushApplied
+		switch {
+		case exists && current.hash() == change.hash():
+			// sent again, e.g. after the answer to an earlier push was lost
+			resp.Results = append(resp.Results, pushResult{UID: change.UID, Status: pushApplied, Record: current})
+			continue
+		case exists && current.Rev > change.Rev:
+			if !change.Modified.After(current.Modified) {
+				fmt.Printf("Conflict on %s: kept the newer change from %s over %s\n", change.UID, current.Origin, change.Origin)
+				resp.Results = append(resp.Results, pushResult{UID: change.UID, Status: pushLost, Record: current})
+				continue
+			}
+			fmt.Printf("Conflict on %s: kept the newer change from %s over %s\n", change.UID, change.Origin, current.Origin)
+			status = pushWon
+		}
+		store.Rev++
+		change.Rev = store.Rev
+		store.Records[change.UID] = change
+		resp.Results = append(resp.Results, pushResult{UID: change.UID, Status: status, Record: change})
+	}
+
+	if store.Rev != s.store.Rev {
+		data, err := json.Marshal(store)
+		if err == nil {
+			err = writeFileAtomic(s.path, data)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.store = store
+	}
+	resp.Rev = s.store.Rev
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// parseTokens extracts the words starting with prefix, e.g. "+" for
+// projects or "@" for contexts, without the prefix and without duplicates
+func parseTokens(description string, prefix string) []string {
+	var tokens []string
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(description) {
+		if !strings.HasPrefix(word, prefix) {
+			continue
+		}
+		token := strings.TrimRight(strings.TrimPrefix(word, prefix), ",.;:!?")
+		if token == "" || seen[token] {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// parseDescription fills the project, context and tag fields from the
+// +project, @context and #tag words in the task's description. The words
+// stay in the description so it still reads naturally. Links are collected
+// from the annotations and notes as well.
+func parseDescription(task *Task) {
+	task.Projects = parseTokens(task.Description, "+")
+	task.Contexts = parseTokens(task.Description, "@")
+	task.Tags = parseTokens(task.Description, "#")
+	task.Links = extractLinks(*task)
+}
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKeys returns the values of the grouping field for a task
+func groupKeys(task Task, by string) []string {
+	switch by {
+	case "project":
+		return task.Projects
+	case "context":
+		return task.Contexts
+	case "tag":
+		return task.Tags
+	}
+	return nil
+}
+
+// groupTasks buckets tasks by project, context or tag. A task with several
+// values appears in each bucket; tasks with none go under the empty key.
+func groupTasks(tasks []Task, by string) ([]string, map[string][]Task) {
+	groups := map[string][]Task{}
+	for _, task := range tasks {
+		keys := groupKeys(task, by)
+		if len(keys) == 0 {
+			groups[""] = append(groups[""], task)
+		}
+		for _, key := range keys {
+			groups[key] = append(groups[key], task)
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := groups[""]; ok {
+		names = append(names, "")
+	}
+	return names, groups
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDescription(t *testing.T) {
+	tests := []struct {
+		description  string
+		wantProjects []string
+		wantContexts []string
+		wantTags     []string
+	}{
+		{description: "Buy milk"},
+		{
+			description:  "Fix login +web @laptop #bug",
+			wantProjects: []string{"web"}, wantContexts: []string{"laptop"}, wantTags: []string{"bug"},
+		},
+		{
+			description:  "Plan +trip, then book +hotel. #urgent!",
+			wantProjects: []string{"trip", "hotel"}, wantTags: []string{"urgent"},
+		},
+		{description: "Repeated +web and +web again", wantProjects: []string{"web"}},
+		{description: "A lone + or @ or # is not a token"},
+		{description: "Mail bob@example.com about C# and a+b"},
+		{description: "@home @phone", wantContexts: []string{"home", "phone"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			task := Task{Description: tt.description}
+			parseDescription(&task)
+			if !reflect.DeepEqual(task.Projects, tt.wantProjects) ||
+				!reflect.DeepEqual(task.Contexts, tt.wantContexts) ||
+				!reflect.DeepEqual(task.Tags, tt.wantTags) {
+				t.Errorf("parseDescription() = %q %q %q, want %q %q %q",
+					task.Projects, task.Contexts, task.Tags, tt.wantProjects, tt.wantContexts, tt.wantTags)
+			}
+			if task.Description != tt.description {
+				t.Errorf("description changed to %q", task.Description)
+			}
+		})
+	}
+}
+
+func TestGroupTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Projects: []string{"web"}},
+		{ID: 2, Projects: []string{"api", "web"}},
+		{ID: 3},
+		{ID: 4, Projects: []string{"api"}},
+	}
+	names, groups := groupTasks(tasks, "project")
+	// named groups in order, then the tasks with none
+	if want := []string{"api", "web", ""}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("groupTasks() names = %q, want %q", names, want)
+	}
+	want := map[string][]int{"api": {2, 4}, "web": {1, 2}, "": {3}}
+	for name, ids := range want {
+		var got []int
+		for _, task := range groups[name] {
+			got = append(got, task.ID)
+		}
+		if !reflect.DeepEqual(got, ids) {
+			t.Errorf("group %q = %v, want %v", name, got, ids)
+		}
+	}
+}
+
+func TestListOptionsMatchTokens(t *testing.T) {
+	task := Task{Description: "Fix login +Web @laptop #bug"}
+	parseDescription(&task)
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{"--project", "web"}, want: true},
+		{args: []string{"--project", "+web"}, want: true},
+		{args: []string{"--project", "api"}, want: false},
+		{args: []string{"--context", "@laptop", "--tag", "#bug"}, want: true},
+		{args: []string{"--context", "phone"}, want: false},
+		{args: []string{"--tag", "BUG"}, want: true},
+	}
+
+	for _, tt := range tests {
+		opts, err := parseListOptions(tt.args)
+		if err != nil {
+			t.Fatalf("parseListOptions(%q) error = %v", tt.args, err)
+		}
+		if got := opts.matches(task); got != tt.want {
+			t.Errorf("list %q matches = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := parseListOptions([]string{"--group", "colour"}); err == nil {
+		t.Errorf("parseListOptions(--group colour) succeeded, want an error")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import "fmt"
+
+func enterRawMode() (func(), error) {
+	return nil, fmt.Errorf("ui is only supported on unix terminals")
+}
+
+func terminalSize() (int, int) {
+	return 24, 80
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// enterRawMode switches the terminal to unbuffered input without echo and
+// returns a function that restores the previous settings
+func enterRawMode() (func(), error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil, fmt.Errorf("ui needs an interactive terminal")
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("reading terminal settings: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("setting raw mode: %w", err)
+	}
+	return func() { stty(saved) }, nil
+}
+
+// terminalSize returns the rows and columns of the terminal
+func terminalSize() (int, int) {
+	var rows, cols int
+	if out, err := stty("size"); err == nil {
+		fmt.Sscan(out, &rows, &cols)
+	}
+	if rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timeEntry is one stretch of work on a task; End is nil while it runs
+type timeEntry struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// runningTask returns the index of the task with a running timer, or -1
+func runningTask(tasks []Task) int {
+	for i, task := range tasks {
+		if task.isRunning() {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t Task) isRunning() bool {
+	n := len(t.TimeEntries)
+	return n > 0 && t.TimeEntries[n-1].End == nil
+}
+
+// trackedTime sums the task's time entries, counting a running one up to now
+func (t Task) trackedTime() time.Duration {
+	var total time.Duration
+	for _, entry := range t.TimeEntries {
+		total += entryOverlap(entry, time.Time{}, time.Time{})
+	}
+	return total
+}
+
+// entryOverlap returns how much of an entry falls in [from, to); a zero
+// bound leaves that side open
+func entryOverlap(entry timeEntry, from, to time.Time) time.Duration {
+	start, end := entry.Start, time.Now()
+	if entry.End != nil {
+		end = *entry.End
+	}
+	if !from.IsZero() && start.Before(from) {
+		start = from
+	}
+	if !to.IsZero() && end.After(to) {
+		end = to
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// stopTimer closes the running entry of a task, if it has one
+func stopTimer(tasks []Task, i int) {
+	if !tasks[i].isRunning() {
+		return
+	}
+	now := time.Now().Truncate(time.Second)
+	tasks[i].TimeEntries[len(tasks[i].TimeEntries)-1].End = &now
+}
+
+// startTimer starts timing a task. Only one timer runs at a time, so a
+// timer running on another task is stopped first; its ID is returned.
+func startTimer(tasks []Task, id int) ([]Task, int, error) {
+	i := findTask(tasks, id)
+	if i < 0 {
+		return nil, 0, fmt.Errorf("no task with ID %d", id)
+	}
+	if tasks[i].isCompleted() {
+		return nil, 0, fmt.Errorf("task %d is completed", id)
+	}
+	if tasks[i].isRunning() {
+		return nil, 0, fmt.Errorf("task %d is already running", id)
+	}
+	stopped := 0
+	if r := runningTask(tasks); r >= 0 {
+		stopTimer(tasks, r)
+		stopped = tasks[r].ID
+	}
+	now := time.Now().Truncate(time.Second)
+	tasks[i].TimeEntries = append(tasks[i].TimeEntries, timeEntry{Start: now})
+	return tasks, stopped, nil
+}
+
+// formatDuration shows a duration in hours and minutes, e.g. 1h05m
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// thisWeek returns the Monday-to-Monday week containing today
+func thisWeek() (time.Time, time.Time) {
+	day := today()
+	from := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	return from, from.AddDate(0, 0, 7)
+}
+
+type reportLine struct {
+	project string
+	task    Task
+	spent   time.Duration
+}
+
+// buildReport sums the time spent on each task in [from, to), listing a
+// task under each of its projects
+func buildReport(tasks []Task, from, to time.Time) []reportLine {
+	var lines []reportLine
+	for _, task := range tasks {
+		var spent time.Duration
+		for _, entry := range task.TimeEntries {
+			spent += entryOverlap(entry, from, to)
+		}
+		if spent == 0 {
+			continue
+		}
+		projects := task.Projects
+		if len(projects) == 0 {
+			projects = []string{""}
+		}
+		for _, project := range projects {
+			lines = append(lines, reportLine{project: project, task: task, spent: spent})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].project != lines[j].project {
+			// tasks without a project go last
+			return lines[j].project == "" || lines[i].project != "" && lines[i].project < lines[j].project
+		}
+		return lines[i].task.ID < lines[j].task.ID
+	})
+	return lines
+}
+
+// reportGroups splits the lines of buildReport into one group per project
+func reportGroups(lines []reportLine) [][]reportLine {
+	var groups [][]reportLine
+	for i := 0; i < len(lines); {
+		j := i
+		for j < len(lines) && lines[j].project == lines[i].project {
+			j++
+		}
+		groups = append(groups, lines[i:j])
+		i = j
+	}
+	return groups
+}
+
+// reportTotal sums the lines; a task in two projects is counted once
+func reportTotal(lines []reportLine) time.Duration {
+	seen := map[int]bool{}
+	var total time.Duration
+	for _, line := range lines {
+		if !seen[line.task.ID] {
+			seen[line.task.ID] = true
+			total += line.spent
+		}
+	}
+	return total
+}
+
+func printReport(lines []reportLine) {
+	if len(lines) == 0 {
+		fmt.Println("No time tracked")
+		return
+	}
+	for _, group := range reportGroups(lines) {
+		name := "+" + group[0].project
+		if group[0].project == "" {
+			name = "(no project)"
+		}
+		fmt.Printf("%-30s %s\n", name, formatDuration(reportTotal(group)))
+		for _, line := range group {
+			fmt.Printf("  %3d. %-40s %s\n", line.task.ID, line.task.Description, formatDuration(line.spent))
+		}
+	}
+	fmt.Printf("%-30s %s\n", "Total", formatDuration(reportTotal(lines)))
+}
+
+// writeReportCSV writes a row per task and project, each project followed
+// by its total, and the grand total last. The kind column tells them apart.
+func writeReportCSV(w io.Writer, lines []reportLine) error {
+	hours := func(d time.Duration) string { return fmt.Sprintf("%.2f", d.Hours()) }
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"kind", "project", "id", "description", "hours"})
+	for _, group := range reportGroups(lines) {
+		for _, line := range group {
+			writer.Write([]string{"task", line.project, fmt.Sprint(line.task.ID), line.task.Description, hours(line.spent)})
+		}
+		writer.Write([]string{"project", group[0].project, "", "", hours(reportTotal(group))})
+	}
+	writer.Write([]string{"total", "", "", "", hours(reportTotal(lines))})
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatTimeEntries writes entries for todo.txt as start/end pairs
+// separated by commas; a running entry has an empty end
+func formatTimeEntries(entries []timeEntry) string {
+	parts := make([]string, len(entries))
+	for i, entry := range entries {
+		end := ""
+		if entry.End != nil {
+			end = entry.End.Format(time.RFC3339Nano)
+		}
+		parts[i] = entry.Start.Format(time.RFC3339Nano) + "/" + end
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseTimeEntries(s string) ([]timeEntry, error) {
+	var entries []timeEntry
+	for _, part := range strings.Split(s, ",") {
+		startText, endText, ok := strings.Cut(part, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid time entry %q", part)
+		}
+		start, err := time.Parse(time.RFC3339Nano, startText)
+		if err != nil {
+			return nil, err
+		}
+		entry := timeEntry{Start: start}
+		if endText != "" {
+			end, err := time.Parse(time.RFC3339Nano, endText)
+			if err != nil {
+				return nil, err
+			}
+			entry.End = &end
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartTimer(t *testing.T) {
+	earlier := time.Now().Add(-time.Hour)
+	tasks := []Task{
+		{ID: 1, Description: "Write report", TimeEntries: []timeEntry{{Start: earlier}}},
+		{ID: 2, Description: "Send report"},
+		{ID: 3, Description: "File expenses", Status: "done"},
+	}
+
+	// starting one timer stops the running one
+	tasks, stopped, err := startTimer(tasks, 2)
+	if err != nil {
+		t.Fatalf("startTimer(2) error = %v", err)
+	}
+	if stopped != 1 || tasks[0].isRunning() || !tasks[1].isRunning() || runningTask(tasks) != 1 {
+		t.Errorf("startTimer(2) stopped %d, running %d, want 1 stopped and 2 running", stopped, runningTask(tasks))
+	}
+	if spent := tasks[0].trackedTime(); spent < 59*time.Minute || spent > 61*time.Minute {
+		t.Errorf("task 1 tracked %s, want an hour", spent)
+	}
+
+	for _, tt := range []struct {
+		id      int
+		wantErr string
+	}{
+		{id: 2, wantErr: "already running"},
+		{id: 3, wantErr: "completed"},
+		{id: 9, wantErr: "no task with ID 9"},
+	} {
+		if _, _, err := startTimer(tasks, tt.id); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("startTimer(%d) error = %v, want one containing %q", tt.id, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEntryOverlap(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2026, 10, 12, hour, 0, 0, 0, time.Local) }
+	end := at(12)
+	entry := timeEntry{Start: at(10), End: &end}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     time.Duration
+	}{
+		{name: "unbounded", want: 2 * time.Hour},
+		{name: "inside the window", from: at(9), to: at(13), want: 2 * time.Hour},
+		{name: "cut at the start", from: at(11), want: time.Hour},
+		{name: "cut at the end", to: at(11), want: time.Hour},
+		{name: "outside the window", from: at(13), to: at(14), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryOverlap(entry, tt.from, tt.to); got != tt.want {
+				t.Errorf("entryOverlap() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// reportTasks have time tracked in the week of 2026-10-12, one task in two
+// projects and one in none
+func reportTasks() []Task {
+	entry := func(day, fromHour, toHour int) timeEntry {
+		end := time.Date(2026, 10, day, toHour, 0, 0, 0, time.Local)
+		return timeEntry{Start: time.Date(2026, 10, day, fromHour, 0, 0, 0, time.Local), End: &end}
+	}
+	tasks := []Task{
+		{ID: 1, Description: "Fix login +web", TimeEntries: []timeEntry{entry(12, 9, 11), entry(13, 9, 10)}},
+		{ID: 2, Description: "Shared client +web +api", TimeEntries: []timeEntry{entry(14, 14, 15)}},
+		{ID: 3, Description: "Inbox zero", TimeEntries: []timeEntry{entry(15, 8, 9)}},
+		{ID: 4, Description: "Last week +api", TimeEntries: []timeEntry{entry(5, 9, 17)}},
+		{ID: 5, Description: "Not started +web"},
+	}
+	for i := range tasks {
+		parseDescription(&tasks[i])
+	}
+	return tasks
+}
+
+func TestBuildReport(t *testing.T) {
+	from := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	lines := buildReport(reportTasks(), from, from.AddDate(0, 0, 7))
+
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.project+"/"+formatDuration(line.spent)+"/"+line.task.Description)
+	}
+	want := []string{
+		"api/1h00m/Shared client +web +api",
+		"web/3h00m/Fix login +web",
+		"web/1h00m/Shared client +web +api",
+		"/1h00m/Inbox zero",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("buildReport() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	from := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	lines := buildReport(reportTasks(), from, from.AddDate(0, 0, 7))
+	got := captureStdout(t, func() { printReport(lines) })
+	// the task in two projects counts once in the total
+	want := strings.Join([]string{
+		"+api                           1h00m",
+		"    2. Shared client +web +api                  1h00m",
+		"+web                           4h00m",
+		"    1. Fix login +web                           3h00m",
+		"    2. Shared client +web +api                  1h00m",
+		"(no project)                   1h00m",
+		"    3. Inbox zero                               1h00m",
+		"Total                          5h00m",
+	}, "\n") + "\n"
+	if got != want {
+		t.Errorf("printReport() printed\n%s\nwant\n%s", got, want)
+	}
+
+	if got := captureStdout(t, func() { printReport(nil) }); got != "No time tracked\n" {
+		t.Errorf("printReport(nil) printed %q", got)
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	from := time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)
+	var buf strings.Builder
+	if err := writeReportCSV(&buf, buildReport(reportTasks(), from, from.AddDate(0, 0, 7))); err != nil {
+		t.Fatalf("writeReportCSV() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"kind,project,id,description,hours",
+		"task,api,2,Shared client +web +api,1.00",
+		"project,api,,,1.00",
+		"task,web,1,Fix login +web,3.00",
+		"task,web,2,Shared client +web +api,1.00",
+		"project,web,,,4.00",
+		"task,,3,Inbox zero,1.00",
+		"project,,,,1.00",
+		"total,,,,5.00",
+	}, "\n") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeReportCSV() wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTimeEntriesText(t *testing.T) {
+	end := time.Date(2026, 10, 12, 11, 0, 0, 0, time.UTC)
+	entries := []timeEntry{
+		{Start: time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC), End: &end},
+		{Start: time.Date(2026, 10, 13, 9, 30, 0, 0, time.UTC)},
+	}
+	text := formatTimeEntries(entries)
+	if want := "2026-10-12T09:00:00Z/2026-10-12T11:00:00Z,2026-10-13T09:30:00Z/"; text != want {
+		t.Errorf("formatTimeEntries() = %q, want %q", text, want)
+	}
+	got, err := parseTimeEntries(text)
+	if err != nil {
+		t.Fatalf("parseTimeEntries() error = %v", err)
+	}
+	if len(got) != 2 || !got[0].Start.Equal(entries[0].Start) || !got[0].End.Equal(end) || got[1].End != nil {
+		t.Errorf("parseTimeEntries() = %+v, want %+v", got, entries)
+	}
+
+	for _, bad := range []string{"2026-10-12T09:00:00Z", "yesterday/", "2026-10-12T09:00:00Z/later"} {
+		if _, err := parseTimeEntries(bad); err == nil {
+			t.Errorf("parseTimeEntries(%q) succeeded, want an error", bad)
+		}
+	}
+}
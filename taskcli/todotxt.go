@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// todo.txt line format (https://github.com/todotxt/todo.txt):
+//
+//	x (A) 2026-10-18 2026-10-01 description +project @context key:value
+//
+// Fields todo.txt has no syntax for are kept as key:value extensions so
+// that converting to todo.txt and back gives the same tasks.
+
+var todoPriorityPattern = regexp.MustCompile(`^\([A-Z]\)$`)
+
+type todoTxtCodec struct{}
+
+func (todoTxtCodec) decode(r io.Reader) ([]Task, error) {
+	var tasks []Task
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		tasks = append(tasks, parseTodoTxtLine(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (todoTxtCodec) encode(w io.Writer, tasks []Task) error {
+	buf := bufio.NewWriter(w)
+	for _, task := range tasks {
+		buf.WriteString(formatTodoTxtLine(task))
+		buf.WriteString("\n")
+	}
+	return buf.Flush()
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse(dateLayout, s)
+	return err == nil
+}
+
+func parseTodoTime(key, value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		// plain dates are accepted too, as other tools write them
+		t, err = time.ParseInLocation(dateLayout, value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", key, value)
+		}
+	}
+	return &t, nil
+}
+
+// applyTodoTxtExtension sets the task field for a key:value extension
+// taskcli knows about. Unknown keys and values that do not parse are left
+// in the description, where other todo.txt tools put them.
+func applyTodoTxtExtension(task *Task, key, value string) bool {
+	switch key {
+	case "id":
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			return false
+		}
+		task.ID = id
+	case "due":
+		t, err := parseTodoTime(key, value)
+		if err != nil {
+			return false
+		}
+		task.Due = t
+	case "pri":
+		p, err := parsePriority(value)
+		if err != nil {
+			return false
+		}
+		task.Priority = p
+	case "created":
+		t, err := parseTodoTime(key, value)
+		if err != nil {
+			return false
+		}
+		task.CreatedAt = t
+	case "modified":
+		t, err := parseTodoTime(key, value)
+		if err != nil {
+			return false
+		}
+		task.ModifiedAt = t
+	case "completed":
+		t, err := parseTodoTime(key, value)
+		if err != nil {
+			return false
+		}
+		task.CompletedAt = t
+	case "status":
+		// the done status is written as the x marker instead
+		if value == config.doneStatus() {
+			return false
+		}
+		task.Status = value
+	case "parent":
+		id, err := parseID(value)
+		if err != nil {
+			return false
+		}
+		task.Parent = id
+	case "rec":
+		// rules are written with underscores for spaces, e.g. rec:every_monday
+		rule := strings.ReplaceAll(value, "_", " ")
+		if _, err := parseRecurrence(rule); err != nil {
+			return false
+		}
+		task.Recur = rule
+	case "rec-day":
+		day, err := strconv.Atoi(value)
+		if err != nil || day < 1 || day > 31 {
+			return false
+		}
+		task.RecurDay = day
+	case "time":
+		entries, err := parseTimeEntries(value)
+		if err != nil {
+			return false
+		}
+		task.TimeEntries = entries
+	case "note":
+		// a note is its time and query-escaped text, e.g.
+		// note:2026-10-18T09:30:00Z|tried+X%2C+failed
+		at, text, ok := strings.Cut(value, "|")
+		t, err := time.Parse(time.RFC3339Nano, at)
+		if !ok || err != nil {
+			return false
+		}
+		if text, err = url.QueryUnescape(text); err != nil {
+			return false
+		}
+		task.Annotations = append(task.Annotations, annotation{Time: t, Text: text})
+	case "notes":
+		notes, err := url.QueryUnescape(value)
+		if err != nil {
+			return false
+		}
+		task.Notes = notes
+	case "source":
+		src, err := parseSource(value)
+		if err != nil {
+			return false
+		}
+		task.Source = src
+	case "uid":
+		task.UID = value
+	case "blocked-by":
+		ids, err := parseIDList(value)
+		if err != nil {
+			return false
+		}
+		task.BlockedBy = ids
+	default:
+		return false
+	}
+	return true
+}
+
+func parseTodoTxtLine(line string) Task {
+	var task Task
+	words := strings.Fields(line)
+
+	if len(words) > 0 && words[0] == "x" {
+		task.Status = config.doneStatus()
+		words = words[1:]
+	}
+	if len(words) > 0 && todoPriorityPattern.MatchString(words[0]) {
+		task.Priority = words[0][1:2]
+		words = words[1:]
+	}
+
+	// a completed task lists its completion date before its creation date
+	var dates []string
+	for len(words) > 0 && len(dates) < 2 && isDate(words[0]) {
+		dates = append(dates, words[0])
+		words = words[1:]
+	}
+	if task.isCompleted() && len(dates) > 0 {
+		task.CompletedAt, _ = parseTodoTime("completion date", dates[0])
+		dates = dates[1:]
+	}
+	if len(dates) > 0 {
+		task.CreatedAt, _ = parseTodoTime("creation date", dates[0])
+	}
+
+	var description []string
+	for _, word := range words {
+		key, value, ok := strings.Cut(word, ":")
+		if ok && key != "" && value != "" && applyTodoTxtExtension(&task, key, value) {
+			continue
+		}
+		description = append(description, word)
+	}
+	task.Description = strings.Join(description, " ")
+	if task.Status == "" {
+		task.Status = config.initialStatus()
+	}
+	parseDescription(&task)
+	return task
+}
+
+func formatTodoTxtLine(task Task) string {
+	var parts []string
+	if task.isCompleted() {
+		parts = append(parts, "x")
+		if task.CompletedAt != nil {
+			parts = append(parts, task.CompletedAt.Format(dateLayout))
+		}
+	} else if task.Priority != "" {
+		parts = append(parts, "("+task.Priority+")")
+	}
+	// todo.txt only allows a creation date on completed tasks after the
+	// completion date, so leave it to the extension otherwise
+	if task.CreatedAt != nil && (!task.isCompleted() || task.CompletedAt != nil) {
+		parts = append(parts, task.CreatedAt.Format(dateLayout))
+	}
+
+	parts = append(parts, strings.Join(strings.Fields(task.Description), " "))
+
+	if task.isCompleted() && task.Priority != "" {
+		parts = append(parts, "pri:"+task.Priority)
+	}
+	if !task.isCompleted() && task.Status != "" && task.Status != config.initialStatus() {
+		parts = append(parts, "status:"+task.Status)
+	}
+	if task.Due != nil {
+		parts = append(parts, "due:"+task.Due.Format(dateLayout))
+	}
+	// full timestamps keep the time of day the dates above drop
+	if task.CreatedAt != nil {
+		parts = append(parts, "created:"+task.CreatedAt.Format(time.RFC3339Nano))
+	}
+	if task.CompletedAt != nil {
+		parts = append(parts, "completed:"+task.CompletedAt.Format(time.RFC3339Nano))
+	}
+	if task.ModifiedAt != nil {
+		parts = append(parts, "modified:"+task.ModifiedAt.Format(time.RFC3339Nano))
+	}
+	if task.Parent != 0 {
+		parts = append(parts, "parent:"+strconv.Itoa(task.Parent))
+	}
+	if len(task.BlockedBy) > 0 {
+		parts = append(parts, "blocked-by:"+formatIDs(task.BlockedBy))
+	}
+	if task.Recur != "" {
+		parts = append(parts, "rec:"+strings.ReplaceAll(task.Recur, " ", "_"))
+	}
+	if task.RecurDay != 0 {
+		parts = append(parts, "rec-day:"+strconv.Itoa(task.RecurDay))
+	}
+	if len(task.TimeEntries) > 0 {
+		parts = append(parts, "time:"+formatTimeEntries(task.TimeEntries))
+	}
+	for _, a := range task.Annotations {
+		parts = append(parts, "note:"+a.Time.Format(time.RFC3339Nano)+"|"+url.QueryEscape(a.Text))
+	}
+	if task.Notes != "" {
+		parts = append(parts, "notes:"+url.QueryEscape(task.Notes))
+	}
+	if task.Source != nil {
+		parts = append(parts, "source:"+formatSource(*task.Source))
+	}
+	if task.UID != "" {
+		parts = append(parts, "uid:"+task.UID)
+	}
+	parts = append(parts, "id:"+strconv.Itoa(task.ID))
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func localTime(year int, month time.Month, day, hour, min int) *time.Time {
+	t := time.Date(year, month, day, hour, min, 0, 0, time.Local)
+	return &t
+}
+
+// fullTask has every field set, with text that needs escaping in each
+// format
+func fullTask() Task {
+	end := localTime(2026, 10, 2, 11, 0)
+	task := Task{
+		ID:          7,
+		Description: "Review the parser +visualiser @work #ui see https://example.com/a?b=c&d=e, then 10:30 call",
+		Status:      "review",
+		Priority:    "B",
+		Due:         localTime(2026, 10, 20, 0, 0),
+		CreatedAt:   localTime(2026, 10, 1, 9, 30),
+		ModifiedAt:  localTime(2026, 10, 3, 8, 15),
+		Parent:      3,
+		BlockedBy:   []int{4, 5},
+		Recur:       "every monday",
+		TimeEntries: []timeEntry{{Start: *localTime(2026, 10, 2, 10, 0), End: end}},
+		UID:         "calendar-app-1234@example.com",
+		Source:      &taskSource{Kind: sourceMarkdown, File: "/home/me/notes/todo list.md", Line: 12, Text: "- [ ] Review the parser", Checked: false},
+		Annotations: []annotation{
+			{Time: *localTime(2026, 10, 2, 12, 0), Text: "tried X|Y, failed; see https://example.com/x"},
+		},
+		Notes: "First line, with a comma;\nsecond line with a \\ backslash\n\n  indented: key:value",
+	}
+	parseDescription(&task)
+	return task
+}
+
+func doneTask() Task {
+	task := Task{
+		ID:          8,
+		Description: "Pay rent +home",
+		Status:      config.doneStatus(),
+		Priority:    "A",
+		CreatedAt:   localTime(2026, 9, 28, 18, 0),
+		CompletedAt: localTime(2026, 10, 1, 7, 45),
+	}
+	parseDescription(&task)
+	return task
+}
+
+// roundTrip encodes the tasks with the codec and decodes them again
+func roundTrip(t *testing.T, codec taskCodec, tasks []Task) ([]Task, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := codec.encode(&buf, tasks); err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	encoded := buf.String()
+	decoded, err := codec.decode(&buf)
+	if err != nil {
+		t.Fatalf("decode() error = %v\n%s", err, encoded)
+	}
+	return decoded, encoded
+}
+
+// checkSameTasks compares task lists field by field through their JSON
+func checkSameTasks(t *testing.T, got, want []Task, encoded string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d tasks, want %d\n%s", len(got), len(want), encoded)
+	}
+	for i := range want {
+		if !sameTask(got[i], want[i]) {
+			gotData, _ := json.MarshalIndent(got[i], "", "  ")
+			wantData, _ := json.MarshalIndent(want[i], "", "  ")
+			t.Errorf("task %d changed in the round trip:\n%s\ngot  %s\nwant %s", want[i].ID, encoded, gotData, wantData)
+		}
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	open := Task{ID: 2, Description: "Plain task", Status: config.initialStatus()}
+	tests := []struct {
+		name  string
+		tasks []Task
+	}{
+		{name: "all fields", tasks: []Task{fullTask()}},
+		{name: "completed with priority", tasks: []Task{doneTask()}},
+		{name: "minimal", tasks: []Task{open}},
+		{name: "several", tasks: []Task{fullTask(), doneTask(), open}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoded := roundTrip(t, todoTxtCodec{}, tt.tasks)
+			checkSameTasks(t, got, tt.tasks, encoded)
+			if lines := strings.Count(encoded, "\n"); lines != len(tt.tasks) {
+				t.Errorf("encoded %d tasks on %d lines:\n%s", len(tt.tasks), lines, encoded)
+			}
+		})
+	}
+}
+
+func TestParseTodoTxtLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		want     Task
+		wantDesc string
+	}{
+		{
+			name: "completed with dates from another tool",
+			line: "x 2026-10-18 2026-10-01 Pay rent +home @errands due:2026-10-20",
+			want: Task{
+				Status:      config.doneStatus(),
+				CompletedAt: localTime(2026, 10, 18, 0, 0),
+				CreatedAt:   localTime(2026, 10, 1, 0, 0),
+				Due:         localTime(2026, 10, 20, 0, 0),
+			},
+			wantDesc: "Pay rent +home @errands",
+		},
+		{
+			name:     "priority and unknown extensions stay in the description",
+			line:     "(A) 2026-10-01 Call Bob at 10:30 foo:bar",
+			want:     Task{Status: config.initialStatus(), Priority: "A", CreatedAt: localTime(2026, 10, 1, 0, 0)},
+			wantDesc: "Call Bob at 10:30 foo:bar",
+		},
+		{
+			name:     "invalid extension value stays in the description",
+			line:     "Water plants due:someday",
+			want:     Task{Status: config.initialStatus()},
+			wantDesc: "Water plants due:someday",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTodoTxtLine(tt.line)
+			if got.Description != tt.wantDesc {
+				t.Errorf("Description = %q, want %q", got.Description, tt.wantDesc)
+			}
+			if got.Status != tt.want.Status || got.Priority != tt.want.Priority {
+				t.Errorf("Status, Priority = %q, %q, want %q, %q", got.Status, got.Priority, tt.want.Status, tt.want.Priority)
+			}
+			for _, field := range []struct {
+				name      string
+				got, want *time.Time
+			}{
+				{"CompletedAt", got.CompletedAt, tt.want.CompletedAt},
+				{"CreatedAt", got.CreatedAt, tt.want.CreatedAt},
+				{"Due", got.Due, tt.want.Due},
+			} {
+				if !sameTime(field.got, field.want) {
+					t.Errorf("%s = %v, want %v", field.name, field.got, field.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// The ui action is a full-screen task list. It never holds the lock while
+// waiting for keys: every change goes through updateTasks, one locked
+// load-modify-save like a CLI run, and the list is reloaded afterwards so
+// changes made from the CLI in the meantime show up too.
+
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyPageUp    = "pgup"
+	keyPageDown  = "pgdown"
+	keyEnter     = "enter"
+	keyEscape    = "esc"
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+)
+
+const uiHelp = "j/k move  a add  x done  e edit  +/- priority  p project  / search  c completed  r reload  q quit"
+
+type uiPrompt struct {
+	label  string
+	text   []rune
+	submit func(ui *uiState, text string)
+}
+
+type uiState struct {
+	tasks    []Task // all tasks as last loaded
+	visible  []Task // tasks passing the filters, in display order
+	cursor   int
+	offset   int
+	showDone bool
+	project  string
+	search   string
+	message  string
+	prompt   *uiPrompt
+	quit     bool
+}
+
+func runUI() error {
+	restore, err := enterRawMode()
+	if err != nil {
+		return err
+	}
+	// draw on the alternate screen so the shell scrollback is left intact
+	fmt.Print("\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		restore()
+	}()
+
+	ui := &uiState{}
+	ui.reload()
+	keys := readKeys()
+	for !ui.quit {
+		ui.render()
+		ui.handleKey(<-keys)
+	}
+	return nil
+}
+
+// readKeys decodes stdin into key names: single characters as themselves,
+// and escape sequences for arrows and paging as the key constants
+func readKeys() <-chan string {
+	runes := make(chan rune)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			r, _, err := reader.ReadRune()
+			if err != nil {
+				close(runes)
+				return
+			}
+			runes <- r
+		}
+	}()
+
+	keys := make(chan string)
+	go func() {
+		// next waits briefly for the rest of an escape sequence
+		next := func() (rune, bool) {
+			select {
+			case r, ok := <-runes:
+				return r, ok
+			case <-time.After(30 * time.Millisecond):
+				return 0, false
+			}
+		}
+		for r := range runes {
+			switch r {
+			case 3:
+				keys <- keyCtrlC
+			case '\r', '\n':
+				keys <- keyEnter
+			case 127, 8:
+				keys <- keyBackspace
+			case 27:
+				if r2, ok := next(); !ok || r2 != '[' {
+					keys <- keyEscape
+					continue
+				}
+				r3, _ := next()
+				switch r3 {
+				case 'A':
+					keys <- keyUp
+				case 'B':
+					keys <- keyDown
+				case '5', '6':
+					next() // the trailing ~
+					if r3 == '5' {
+						keys <- keyPageUp
+					} else {
+						keys <- keyPageDown
+					}
+				}
+			default:
+				keys <- string(r)
+			}
+		}
+		keys <- keyCtrlC
+	}()
+	return keys
+}
+
+// reload reads the tasks again and reapplies the filters, keeping the
+// cursor on the same task where possible
+func (ui *uiState) reload() {
+	selected := 0
+	if task, ok := ui.selected(); ok {
+		selected = task.ID
+	}
+	tasks, err := readTasks()
+	if err != nil {
+		ui.message = "Error loading tasks: " + err.Error()
+		return
+	}
+	ui.tasks = tasks
+	ui.applyFilters()
+	for i, task := range ui.visible {
+		if task.ID == selected {
+			ui.cursor = i
+		}
+	}
+}
+
+func (ui *uiState) applyFilters() {
+	ui.visible = nil
+	search := strings.ToLower(ui.search)
+	for _, task := range ui.tasks {
+		if task.isCompleted() && !ui.showDone {
+			continue
+		}
+		if ui.project != "" && !containsFold(task.Projects, ui.project) {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(task.Description), search) {
+			continue
+		}
+		ui.visible = append(ui.visible, task)
+	}
+	// open tasks first, by priority
+	sortTasks(ui.visible, "priority")
+	var open, done []Task
+	for _, task := range ui.visible {
+		if task.isCompleted() {
+			done = append(done, task)
+		} else {
+			open = append(open, task)
+		}
+	}
+	ui.visible = append(open, done...)
+	if ui.cursor >= len(ui.visible) {
+		ui.cursor = len(ui.visible) - 1
+	}
+	if ui.cursor < 0 {
+		ui.cursor = 0
+	}
+}
+
+func (ui *uiState) selected() (Task, bool) {
+	if ui.cursor < 0 || ui.cursor >= len(ui.visible) {
+		return Task{}, false
+	}
+	return ui.visible[ui.cursor], true
+}
+
+// update applies a change through the shared load-modify-save path
+func (ui *uiState) update(op string, change func([]Task) ([]Task, error), done string) {
+	if _, err := updateTasks(op, change); err != nil {
+		ui.message = "Error: " + err.Error()
+	} else {
+		ui.message = done
+	}
+	ui.reload()
+}
+
+func (ui *uiState) ask(label string, initial string, submit func(ui *uiState, text string)) {
+	ui.prompt = &uiPrompt{label: label, text: []rune(initial), submit: submit}
+}
+
+func (ui *uiState) handleKey(key string) {
+	if ui.prompt != nil {
+		ui.handlePromptKey(key)
+		return
+	}
+	ui.message = ""
+	_, rows := ui.listArea()
+
+	switch key {
+	case "q", keyCtrlC:
+		ui.quit = true
+	case "j", keyDown:
+		ui.cursor++
+	case "k", keyUp:
+		ui.cursor--
+	case keyPageDown, " ":
+		ui.cursor += rows
+	case keyPageUp:
+		ui.cursor -= rows
+	case "g":
+		ui.cursor = 0
+	case "G":
+		ui.cursor = len(ui.visible) - 1
+	case "r":
+		ui.reload()
+		ui.message = "Reloaded"
+	case "c":
+		ui.showDone = !ui.showDone
+		ui.applyFilters()
+	case "a":
+		ui.ask("Add", "", func(ui *uiState, text string) {
+			if text == "" {
+				return
+			}
+			ui.update("ui: add "+text, func(tasks []Task) ([]Task, error) {
+				return addTask(tasks, text), nil
+			}, "Added: "+text)
+		})
+	case "/":
+		ui.ask("Search", ui.search, func(ui *uiState, text string) {
+			ui.search = text
+			ui.applyFilters()
+		})
+	case "p":
+		ui.ask("Project (empty for all)", ui.project, func(ui *uiState, text string) {
+			ui.project = strings.TrimPrefix(text, "+")
+			ui.applyFilters()
+		})
+	case keyEscape:
+		ui.search, ui.project = "", ""
+		ui.applyFilters()
+	}
+
+	task, ok := ui.selected()
+	if !ok {
+		ui.clampCursor()
+		return
+	}
+	switch key {
+	case "x", keyEnter:
+		ui.toggleDone(task)
+	case "e":
+		ui.ask(fmt.Sprintf("Edit %d", task.ID), task.Description, func(ui *uiState, text string) {
+			if text == "" || text == task.Description {
+				return
+			}
+			ui.update(fmt.Sprintf("ui: edit %d %s", task.ID, text), func(tasks []Task) ([]Task, error) {
+				return editTask(tasks, task.ID, text)
+			}, fmt.Sprintf("Updated %d", task.ID))
+		})
+	case "+", "-":
+		priority := shiftPriority(task.Priority, key == "+")
+		ui.update(fmt.Sprintf("ui: prio %d %s", task.ID, priority), func(tasks []Task) ([]Task, error) {
+			return setPriority(tasks, task.ID, priority)
+		}, fmt.Sprintf("Priority of %d set to %s", task.ID, orNone(priority)))
+	}
+	ui.clampCursor()
+}
+
+func (ui *uiState) toggleDone(task Task) {
+	if task.isCompleted() {
+		ui.update(fmt.Sprintf("ui: undone %d", task.ID), func(tasks []Task) ([]Task, error) {
+			return reopenTask(tasks, task.ID)
+		}, fmt.Sprintf("Reopened %d", task.ID))
+		return
+	}
+	ui.update(fmt.Sprintf("ui: done %d", task.ID), func(tasks []Task) ([]Task, error) {
+		children, blockers := openDependencies(tasks, task.ID)
+		if len(children) > 0 || len(blockers) > 0 {
+			return nil, fmt.Errorf("task %d has open subtasks or blockers; use done --force", task.ID)
+		}
+		return completeTask(tasks, task.ID)
+	}, fmt.Sprintf("Completed %d", task.ID))
+}
+
+func (ui *uiState) handlePromptKey(key string) {
+	p := ui.prompt
+	switch key {
+	case keyEscape, keyCtrlC:
+		ui.prompt = nil
+	case keyEnter:
+		ui.prompt = nil
+		p.submit(ui, strings.TrimSpace(string(p.text)))
+	case keyBackspace:
+		if len(p.text) > 0 {
+			p.text = p.text[:len(p.text)-1]
+		}
+	default:
+		if r := []rune(key); len(r) == 1 && r[0] >= ' ' {
+			p.text = append(p.text, r[0])
+		}
+	}
+}
+
+// shiftPriority moves a priority one step up (towards A) or down; going
+// below the lowest priority clears it
+func shiftPriority(current string, up bool) string {
+	rank := priorityRank(current)
+	if up {
+		rank--
+	} else {
+		rank++
+	}
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(priorities) {
+		return ""
+	}
+	return priorities[rank]
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func (ui *uiState) clampCursor() {
+	if ui.cursor >= len(ui.visible) {
+		ui.cursor = len(ui.visible) - 1
+	}
+	if ui.cursor < 0 {
+		ui.cursor = 0
+	}
+}
+
+// listArea returns the terminal width and the number of rows for tasks
+func (ui *uiState) listArea() (int, int) {
+	rows, cols := terminalSize()
+	// header, two rules, message and help lines
+	return cols, rows - 5
+}
+
+func (ui *uiState) render() {
+	cols, rows := ui.listArea()
+	if rows < 1 {
+		rows = 1
+	}
+	if ui.cursor < ui.offset {
+		ui.offset = ui.cursor
+	}
+	if ui.cursor >= ui.offset+rows {
+		ui.offset = ui.cursor - rows + 1
+	}
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	header := fmt.Sprintf("taskcli  %s  %d shown", tasksFile, len(ui.visible))
+	if ui.project != "" {
+		header += "  project:+" + ui.project
+	}
+	if ui.search != "" {
+		header += "  search:" + ui.search
+	}
+	if ui.showDone {
+		header += "  (with completed)"
+	}
+	b.WriteString(truncate(header, cols) + "\r\n")
+	b.WriteString(strings.Repeat("-", cols) + "\r\n")
+
+	for row := 0; row < rows; row++ {
+		i := ui.offset + row
+		if i < len(ui.visible) {
+			task := ui.visible[i]
+			line := padRight(truncate(formatTask(task), cols), cols)
+			switch {
+			case i == ui.cursor:
+				line = "\033[7m" + line + colorReset
+			case task.isOverdue():
+				line = colorRed + line + colorReset
+			}
+			b.WriteString(line)
+		}
+		b.WriteString("\r\n")
+	}
+
+	b.WriteString(strings.Repeat("-", cols) + "\r\n")
+	if ui.prompt != nil {
+		b.WriteString(truncate(ui.prompt.label+": "+string(ui.prompt.text)+"_", cols) + "\r\n")
+	} else {
+		b.WriteString(truncate(ui.message, cols) + "\r\n")
+	}
+	b.WriteString(truncate(uiHelp, cols))
+	fmt.Print(b.String())
+}
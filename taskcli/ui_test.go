@@ -0,0 +1,148 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShiftPriority(t *testing.T) {
+	tests := []struct {
+		current string
+		up      bool
+		want    string
+	}{
+		{current: "", up: true, want: "D"},
+		{current: "C", up: true, want: "B"},
+		{current: "A", up: true, want: "A"},
+		{current: "B", up: false, want: "C"},
+		{current: "D", up: false, want: ""},
+		{current: "", up: false, want: ""},
+	}
+	for _, tt := range tests {
+		if got := shiftPriority(tt.current, tt.up); got != tt.want {
+			t.Errorf("shiftPriority(%q, %v) = %q, want %q", tt.current, tt.up, got, tt.want)
+		}
+	}
+}
+
+// newTestUI returns a ui over a temporary tasks file holding the tasks
+func newTestUI(t *testing.T, tasks []Task) *uiState {
+	t.Helper()
+	useTempTasksFile(t)
+	for i := range tasks {
+		parseDescription(&tasks[i])
+	}
+	if err := saveTasks(tasks); err != nil {
+		t.Fatal(err)
+	}
+	ui := &uiState{}
+	ui.reload()
+	if ui.message != "" {
+		t.Fatal(ui.message)
+	}
+	return ui
+}
+
+func visibleIDs(ui *uiState) []int {
+	var ids []int
+	for _, task := range ui.visible {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestUINavigation(t *testing.T) {
+	ui := newTestUI(t, []Task{
+		{ID: 1, Description: "Low", Status: "todo", Priority: "C"},
+		{ID: 2, Description: "Done", Status: "done"},
+		{ID: 3, Description: "High +web", Status: "todo", Priority: "A"},
+		{ID: 4, Description: "Middle +web", Status: "doing", Priority: "B"},
+	})
+
+	// open tasks by priority, completed ones hidden
+	if got := visibleIDs(ui); !reflect.DeepEqual(got, []int{3, 4, 1}) {
+		t.Fatalf("visible = %v, want [3 4 1]", got)
+	}
+	for _, step := range []struct {
+		key  string
+		want int
+	}{
+		{"j", 1}, {keyDown, 2}, {"j", 2}, {"k", 1}, {"g", 0}, {keyUp, 0}, {"G", 2}, {keyPageUp, 0}, {keyPageDown, 2},
+	} {
+		ui.handleKey(step.key)
+		if ui.cursor != step.want {
+			t.Fatalf("cursor after %q = %d, want %d", step.key, ui.cursor, step.want)
+		}
+	}
+
+	ui.handleKey("c")
+	if got := visibleIDs(ui); !reflect.DeepEqual(got, []int{3, 4, 1, 2}) {
+		t.Errorf("visible with completed = %v, want [3 4 1 2]", got)
+	}
+
+	// typing into the project prompt, with a backspace, then escape clears
+	for _, key := range []string{"p", "+", "w", "e", "x", keyBackspace, "b", keyEnter} {
+		ui.handleKey(key)
+	}
+	if ui.project != "web" || !reflect.DeepEqual(visibleIDs(ui), []int{3, 4}) {
+		t.Errorf("project filter = %q showing %v, want web showing [3 4]", ui.project, visibleIDs(ui))
+	}
+	ui.handleKey(keyEscape)
+	if ui.project != "" || len(ui.visible) != 4 {
+		t.Errorf("escape left project %q showing %v", ui.project, visibleIDs(ui))
+	}
+
+	ui.handleKey("/")
+	ui.handleKey("x")
+	ui.handleKey(keyEscape)
+	if ui.prompt != nil || ui.search != "" {
+		t.Errorf("escape in a prompt left prompt %v and search %q", ui.prompt, ui.search)
+	}
+
+	ui.handleKey("q")
+	if !ui.quit {
+		t.Errorf("q did not quit")
+	}
+}
+
+func TestUIChanges(t *testing.T) {
+	ui := newTestUI(t, []Task{
+		{ID: 1, Description: "Release", Status: "todo", Priority: "C"},
+		{ID: 2, Description: "Changelog", Status: "todo", Priority: "A", Parent: 1},
+	})
+
+	// the cursor starts on 2, the higher priority
+	ui.handleKey("+")
+	if task, _ := ui.selected(); task.ID != 2 || task.Priority != "A" {
+		t.Errorf("+ on priority A gave %+v", task)
+	}
+	ui.handleKey("-")
+	if task, _ := ui.selected(); task.Priority != "B" {
+		t.Errorf("- gave priority %q, want B", task.Priority)
+	}
+
+	// the parent cannot be completed while its subtask is open
+	ui.handleKey("j")
+	ui.handleKey("x")
+	if !strings.Contains(ui.message, "open subtasks or blockers") {
+		t.Errorf("completing a parent said %q, want a refusal", ui.message)
+	}
+	ui.handleKey("k")
+	ui.handleKey("x")
+	ui.handleKey("c")
+	if i := findTask(ui.tasks, 2); i < 0 || !ui.tasks[i].isCompleted() {
+		t.Errorf("x did not complete task 2: %+v", ui.tasks)
+	}
+
+	for _, key := range []string{"a", "N", "e", "w", keyEnter} {
+		ui.handleKey(key)
+	}
+	tasks, err := readTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 3 || tasks[2].Description != "New" {
+		t.Errorf("tasks after adding = %+v", tasks)
+	}
+}
@@ -0,0 +1,145 @@
+// Dashboard for taskcli serve. It only talks to the JSON API, and reloads
+// the list every few seconds so changes made from the CLI show up.
+
+const list = document.getElementById("tasks");
+const errorBox = document.getElementById("error");
+const filterInput = document.getElementById("filter");
+const sortSelect = document.getElementById("sort");
+let statuses = [];
+let doneStatus = "done";
+
+async function api(method, path, body) {
+    const options = { method, headers: {} };
+    if (body !== undefined) {
+        options.headers["Content-Type"] = "application/json";
+        options.body = JSON.stringify(body);
+    }
+    const response = await fetch(path, options);
+    if (response.status === 204) {
+        return null;
+    }
+    const data = await response.json();
+    if (!response.ok) {
+        throw new Error(data.error);
+    }
+    return data;
+}
+
+function showError(err) {
+    errorBox.hidden = !err;
+    errorBox.textContent = err ? err.message : "";
+}
+
+function today() {
+    const now = new Date();
+    return new Date(now.getFullYear(), now.getMonth(), now.getDate());
+}
+
+function span(className, text) {
+    const el = document.createElement("span");
+    el.className = className;
+    el.textContent = text;
+    return el;
+}
+
+function renderTask(task) {
+    const item = document.createElement("li");
+    const done = task.status === doneStatus;
+    item.classList.toggle("done", done);
+
+    const check = document.createElement("input");
+    check.type = "checkbox";
+    check.checked = done;
+    check.addEventListener("change", () =>
+        change("POST", `/api/tasks/${task.id}/${check.checked ? "done" : "undone"}`));
+    item.append(check, span("id", task.id));
+
+    if (task.priority) {
+        item.append(span("priority", `(${task.priority})`));
+    }
+    const description = span("description", task.description);
+    description.title = "Double-click to edit";
+    description.addEventListener("dblclick", () => {
+        const text = prompt("Description", task.description);
+        if (text && text !== task.description) {
+            change("PATCH", `/api/tasks/${task.id}`, { description: text });
+        }
+    });
+    item.append(description);
+
+    if (!done && task.status !== statuses[0]) {
+        item.append(span("status", task.status));
+    }
+    if (task.due) {
+        const due = new Date(task.due);
+        item.append(span("due", "due " + due.toLocaleDateString()));
+        item.classList.toggle("overdue", !done && due < today());
+    }
+
+    const remove = document.createElement("button");
+    remove.className = "delete";
+    remove.textContent = "✕";
+    remove.title = "Delete";
+    remove.addEventListener("click", () => {
+        if (confirm(`Delete task ${task.id}?`)) {
+            change("DELETE", `/api/tasks/${task.id}`);
+        }
+    });
+    item.append(remove);
+    return item;
+}
+
+async function refresh() {
+    const params = new URLSearchParams({ sort: sortSelect.value });
+    if (filterInput.value.trim()) {
+        params.set("filter", filterInput.value);
+    }
+    try {
+        const tasks = await api("GET", "/api/tasks?" + params);
+        list.replaceChildren(...tasks.map(renderTask));
+        showError(null);
+    } catch (err) {
+        showError(err);
+    }
+}
+
+async function change(method, path, body) {
+    try {
+        await api(method, path, body);
+    } catch (err) {
+        showError(err);
+        return;
+    }
+    refresh();
+}
+
+document.getElementById("add").addEventListener("submit", async (event) => {
+    event.preventDefault();
+    const body = { description: document.getElementById("description").value };
+    const priority = document.getElementById("priority").value;
+    const due = document.getElementById("due").value.trim();
+    if (priority) {
+        body.priority = priority;
+    }
+    if (due) {
+        body.due = due;
+    }
+    await change("POST", "/api/tasks", body);
+    if (errorBox.hidden) {
+        event.target.reset();
+    }
+});
+
+document.getElementById("query").addEventListener("submit", (event) => {
+    event.preventDefault();
+    refresh();
+});
+filterInput.addEventListener("change", refresh);
+sortSelect.addEventListener("change", refresh);
+
+api("GET", "/api/statuses").then((names) => {
+    statuses = names;
+    doneStatus = names[names.length - 1];
+    refresh();
+    setInterval(refresh, 5000);
+}, showError);